@@ -0,0 +1,170 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+)
+
+// Diagnostic is an error found in a program, with the phase that found it
+// ("syntax", "naming", "type" or "recursion") and the span it was found at.
+type Diagnostic struct {
+	Phase   string
+	Message string
+	Span    parser.Span
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s error: %s", d.Span.Start.Line, d.Span.Start.Column, d.Phase, d.Message)
+}
+
+// Analysis is a parsed and analysed program together with its symbol
+// table, which later analyses do not change. Root is nil when the program
+// does not parse.
+type Analysis struct {
+	Source      string
+	Root        *parser.ASTNode
+	Diagnostics []Diagnostic
+	symbols     SymbolTable
+}
+
+// Analyse runs every phase up to the recursion check on source and
+// collects their errors instead of stopping at the first one. The type
+// and recursion checks only run once naming succeeded.
+func Analyse(source string) *Analysis {
+	analysis := &Analysis{Source: source, Diagnostics: make([]Diagnostic, 0)}
+	root, err := parser.ParseSource(source)
+	if err != nil {
+		syntaxError := err.(*parser.SyntaxError)
+		analysis.Diagnostics = append(analysis.Diagnostics, Diagnostic{"syntax", syntaxError.Message, syntaxError.Span})
+		return analysis
+	}
+	analysis.Root = root
+
+	if analysis.phase("naming", func() { AnalyseProgram(root) }) {
+		analysis.phase("type", func() { TypeCheckProgram(root) })
+		analysis.phase("recursion", func() { CheckRecursion(root) })
+	}
+	analysis.symbols = symbolTable
+	return analysis
+}
+
+// phase runs a phase and records the error it panics with, if any, at the
+// node it was working on. It reports whether the phase succeeded.
+func (a *Analysis) phase(name string, run func()) (ok bool) {
+	visiting = nil
+	defer func() {
+		if r := recover(); r != nil {
+			span := a.Root.Span
+			if visiting != nil && !visiting.Span.IsZero() {
+				span = visiting.Span
+			}
+			a.Diagnostics = append(a.Diagnostics, Diagnostic{name, fmt.Sprint(r), span})
+			ok = false
+		}
+	}()
+
+	run()
+	return true
+}
+
+// Declaration returns the VAR or NAME node declaring the variable,
+// procedure or function that node refers to, or nil if node is not a
+// resolved identifier. A declaration is its own declaration.
+func (a *Analysis) Declaration(node *parser.ASTNode) *parser.ASTNode {
+	id, ok := a.declarationID(node)
+	if !ok {
+		return nil
+	}
+	return parser.GetNodeByID(a.Root, id)
+}
+
+func (a *Analysis) declarationID(node *parser.ASTNode) (int, bool) {
+	if a.Root == nil || node == nil || (node.Type != VAR && node.Type != NAME) {
+		return 0, false
+	}
+	info, ok := a.symbols[int(node.ID)]
+	if !ok {
+		return 0, false
+	}
+	// Uses of globals refer to the glob section rather than to the
+	// declaration; they share its unique name.
+	if globals := a.Root.Children[0]; info.declarationNode == int(globals.ID) {
+		for _, global := range globals.Children {
+			if a.symbols[int(global.ID)].uniqueID == info.uniqueID {
+				return int(global.ID), true
+			}
+		}
+		return 0, false
+	}
+	return info.declarationNode, true
+}
+
+// References returns the declaration of what node refers to followed by
+// every use of it, in source order.
+func (a *Analysis) References(node *parser.ASTNode) []*parser.ASTNode {
+	declaration := a.Declaration(node)
+	if declaration == nil {
+		return nil
+	}
+	output := []*parser.ASTNode{declaration}
+	parser.Inspect(a.Root, func(n *parser.ASTNode) bool {
+		if id, ok := a.declarationID(n); ok && n != declaration && id == int(declaration.ID) {
+			output = append(output, n)
+		}
+		return true
+	})
+	return output
+}
+
+// Symbol describes a declared name: what it is, the type of its values
+// and the name it gets in the generated BASIC.
+type Symbol struct {
+	Name      string
+	Kind      string
+	Type      string
+	BasicName string
+}
+
+// Describe returns the symbol node declares or refers to.
+func (a *Analysis) Describe(node *parser.ASTNode) (Symbol, bool) {
+	declaration := a.Declaration(node)
+	if declaration == nil {
+		return Symbol{}, false
+	}
+	info := a.symbols[int(declaration.ID)]
+	symbol := Symbol{Name: info.symbolName, Type: "numeric", BasicName: info.uniqueID}
+	switch {
+	case declaration.Type == NAME && info.scopeLevel == int(a.Root.Children[1].ID):
+		symbol.Kind, symbol.Type = "procedure", "none"
+	case declaration.Type == NAME:
+		symbol.Kind = "function"
+	case info.scopeLevel == int(a.Root.Children[0].ID):
+		symbol.Kind = "global variable"
+	case info.scopeLevel == int(a.Root.Children[3].ID):
+		symbol.Kind = "variable"
+	default:
+		def := parser.GetNodeByID(a.Root, info.scopeLevel)
+		symbol.Kind = "local variable"
+		for _, param := range parameterList(def) {
+			if param == declaration {
+				symbol.Kind = "parameter"
+			}
+		}
+	}
+	return symbol, true
+}
+
+// TermType returns "numeric" or "boolean" for a TERM node of a program
+// without type errors.
+func (a *Analysis) TermType(term *parser.ASTNode) (t string, ok bool) {
+	if term == nil || term.Type != TERM {
+		return "", false
+	}
+	defer func() {
+		if recover() != nil {
+			t, ok = "", false
+		}
+	}()
+
+	return checkTerm(term), true
+}
@@ -0,0 +1,109 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"strings"
+	"testing"
+)
+
+const analysisProgram = `glob { g }
+proc {
+	p(a) { local { b } b = a; g = b }
+}
+func {
+	f(n) { local { } n = (n plus 1); return n }
+}
+main {
+	var { x }
+	x = f(g);
+	p(x);
+	if (x > 1) { print g } else { halt }
+}`
+
+// nodeAt returns the node at the first occurrence of text after skipping
+// the given number of earlier ones.
+func nodeAt(t *testing.T, analysis *Analysis, text string, skip int) *parser.ASTNode {
+	t.Helper()
+	offset := -1
+	for i := 0; i <= skip; i++ {
+		next := strings.Index(analysis.Source[offset+1:], text)
+		if next < 0 {
+			t.Fatalf("no occurrence %d of %q", skip, text)
+		}
+		offset += next + 1
+	}
+	return parser.NodeAt(analysis.Root, offset)
+}
+
+func TestAnalyse(t *testing.T) {
+	analysis := Analyse(analysisProgram)
+	if len(analysis.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", analysis.Diagnostics)
+	}
+
+	tests := []struct {
+		name        string
+		text        string
+		skip        int
+		kind        string
+		declaration string
+		references  int
+	}{
+		{"Testing a global", "g }", 0, "global variable", "g", 4},
+		{"Testing a use of a global in main", "g);", 0, "global variable", "g", 4},
+		{"Testing a parameter", "a)", 0, "parameter", "a", 2},
+		{"Testing a local", "b =", 0, "local variable", "b", 3},
+		{"Testing a procedure call", "p(x)", 0, "procedure", "p", 2},
+		{"Testing a function call", "f(g)", 0, "function", "f", 2},
+		{"Testing a main variable", "x > 1", 0, "variable", "x", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := nodeAt(t, analysis, tt.text, tt.skip)
+			symbol, ok := analysis.Describe(node)
+			if !ok || symbol.Kind != tt.kind || symbol.Name != tt.declaration {
+				t.Fatalf("got %+v for %s node %q", symbol, node.Type, node.Name)
+			}
+			declaration := analysis.Declaration(node)
+			if declaration == nil || declaration.Name != tt.declaration || symbol.BasicName != symbolTable[int(declaration.ID)].uniqueID {
+				t.Errorf("got declaration %v", declaration)
+			}
+			references := analysis.References(node)
+			if len(references) != tt.references || references[0] != declaration {
+				t.Errorf("got %d references, expected %d starting with the declaration", len(references), tt.references)
+			}
+		})
+	}
+
+	if got, ok := analysis.TermType(nodeAt(t, analysis, "(x > 1)", 0)); !ok || got != "boolean" {
+		t.Errorf("got term type %q, expected boolean", got)
+	}
+}
+
+func TestAnalyseDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		phase   string
+		message string
+		line    int
+		column  int
+	}{
+		{"Testing a syntax error", "glob { }\nproc { }\nfunc { }\nmain { var { } x = }", "syntax", "syntax error", 4, 20},
+		{"Testing a lexical error", "glob { }\nproc { }\nfunc { }\nmain { var { } halt # }", "syntax", "Lexical error", 4, 21},
+		{"Testing an undeclared variable", "glob { }\nproc { }\nfunc { }\nmain {\n\tvar { }\n\ty = 1\n}", "naming", "undeclared-variable: y", 6, 2},
+		{"Testing a type error", "glob { }\nproc { }\nfunc { }\nmain {\n\tvar { x }\n\tx = (x > 1)\n}", "type", "expected numeric type", 6, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := Analyse(tt.input).Diagnostics
+			if len(diagnostics) != 1 {
+				t.Fatalf("got diagnostics %v, expected one", diagnostics)
+			}
+			d := diagnostics[0]
+			if d.Phase != tt.phase || !strings.Contains(d.Message, tt.message) || d.Span.Start.Line != tt.line || d.Span.Start.Column != tt.column {
+				t.Errorf("got %s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package analyser
+
+import (
+	"SPL-compiler/backend"
+	"SPL-compiler/bytecode"
+	"SPL-compiler/parser"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// builtinBackend adapts one of the translations in this package to the
+// backend.Backend interface.
+type builtinBackend struct {
+	name      string
+	extension string
+	emit      func(program *backend.Program) ([]byte, error)
+}
+
+func (b builtinBackend) Name() string          { return b.name }
+func (b builtinBackend) FileExtension() string { return b.extension }
+
+func (b builtinBackend) Emit(program *backend.Program) ([]byte, error) {
+	return b.emit(program)
+}
+
+// fromAST wraps a translation that works on the analysed AST.
+func fromAST(translate func(root *parser.ASTNode) (string, error)) func(*backend.Program) ([]byte, error) {
+	return func(program *backend.Program) ([]byte, error) {
+		code, err := translate(program.AST)
+		return []byte(code), err
+	}
+}
+
+// fromIntermediate wraps a translation that works on the intermediate code.
+// The translation gets its own copy since BASIC rewrites it in place.
+func fromIntermediate(translate func(program []string) (string, error)) func(*backend.Program) ([]byte, error) {
+	return func(program *backend.Program) ([]byte, error) {
+		code, err := translate(append([]string{}, program.Intermediate...))
+		return []byte(code), err
+	}
+}
+
+func init() {
+	for _, b := range []builtinBackend{
+		{"basic", "txt", func(program *backend.Program) ([]byte, error) {
+			dialect, options, err := basicSettings(program)
+			if err != nil {
+				return nil, err
+			}
+			instrs, err := ValidateTranslateToBasicDialect(program.Intermediate, dialect, options)
+			return []byte(strings.Join(instrs, "\n")), err
+		}},
+		{"sourcemap", "map.json", func(program *backend.Program) ([]byte, error) {
+			dialect, options, err := basicSettings(program)
+			if err != nil {
+				return nil, err
+			}
+			return ValidateBasicSourceMap(program.Intermediate, dialect, options, program.Filename)
+		}},
+		{"html", "html", func(program *backend.Program) ([]byte, error) {
+			dialect, options, err := basicSettings(program)
+			if err != nil {
+				return nil, err
+			}
+			code, err := ValidateGenerateReport(program.AST, program.Intermediate, dialect, options)
+			return []byte(code), err
+		}},
+		{"x86", "s", fromIntermediate(ValidateTranslateToX86)},
+		{"c", "c", fromAST(ValidateTranslateToC)},
+		{"wat", "wat", fromAST(ValidateTranslateToWat)},
+		{"wasm", "wasm", func(program *backend.Program) ([]byte, error) {
+			return ValidateTranslateToWasm(program.AST)
+		}},
+		{"llvm", "ll", fromAST(ValidateTranslateToLLVM)},
+		{"bytecode", "splc", func(program *backend.Program) ([]byte, error) {
+			compiled, err := ValidateCompileToBytecode(program.AST)
+			if err != nil {
+				return nil, err
+			}
+			return bytecode.Encode(compiled), nil
+		}},
+		{"js", "js", fromAST(ValidateTranslateToJavaScript)},
+		{"python", "py", fromAST(ValidateTranslateToPython)},
+	} {
+		backend.Register(b)
+	}
+}
+
+// basicSettings reads the BASIC backend options given on the command line.
+func basicSettings(program *backend.Program) (*BasicDialect, BasicOptions, error) {
+	options := DefaultBasicOptions
+	dialect, err := LookupBasicDialect(program.Option("dialect", "spl"))
+	if err != nil {
+		return nil, options, err
+	}
+	for name, field := range map[string]*int{
+		"line-start":     &options.Numbering.Start,
+		"line-increment": &options.Numbering.Increment,
+		"line-max":       &options.Numbering.Max,
+	} {
+		if *field, err = strconv.Atoi(program.Option(name, strconv.Itoa(*field))); err != nil {
+			return nil, options, fmt.Errorf("option %s: %v", name, err)
+		}
+	}
+	for name, field := range map[string]*bool{
+		"checked":         &options.Checked,
+		"rem-comments":    &options.Comments,
+		"source-comments": &options.SourceComments,
+	} {
+		if *field, err = strconv.ParseBool(program.Option(name, "false")); err != nil {
+			return nil, options, fmt.Errorf("option %s: %v", name, err)
+		}
+	}
+	options.Origins = program.Origins
+	options.Source = program.Source
+	return dialect, options, nil
+}
@@ -0,0 +1,40 @@
+package analyser
+
+import (
+	"testing"
+
+	"SPL-compiler/backend"
+)
+
+func TestBuiltinBackends(t *testing.T) {
+	names := []string{"basic", "sourcemap", "html", "x86", "c", "wat", "wasm", "llvm", "bytecode", "js", "python"}
+	for _, tt := range backendPrograms {
+		t.Run(tt.name, func(t *testing.T) {
+			root := analyseForTest(t, tt.input)
+			intermediate, origins, err := ValidateCodeGeneration(root)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			program := &backend.Program{Source: tt.input, AST: root, Intermediate: intermediate, Origins: origins}
+			before := append([]string{}, intermediate...)
+
+			for _, name := range names {
+				b, err := backend.Lookup(name)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				code, err := b.Emit(program)
+				if err != nil {
+					t.Errorf("%s: unexpected error: %v", name, err)
+				} else if len(code) == 0 {
+					t.Errorf("%s: empty output", name)
+				}
+			}
+			for i := range before {
+				if program.Intermediate[i] != before[i] {
+					t.Fatalf("backends modified the intermediate code: %q became %q", before[i], program.Intermediate[i])
+				}
+			}
+		})
+	}
+}
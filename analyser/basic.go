@@ -1,6 +1,8 @@
 package analyser
 
 import (
+	"SPL-compiler/lexer"
+	"SPL-compiler/parser"
 	"fmt"
 	"strings"
 )
@@ -16,35 +18,262 @@ func ValidateTranslateToBasic(program []string) (instrs []string, err error) {
 	return program, nil
 }
 
+// TranslateToBasic rewrites the intermediate code in place into the default
+// "spl" dialect. The default options translate every instruction into one
+// line; it panics rather than drop lines if that ever changes.
 func TranslateToBasic(program []string) {
-	labelMap := getLabel(program)
-	addLineNumbers(labelMap, program)
+	translated := TranslateToBasicDialect(program, BasicDialects["spl"], DefaultBasicOptions)
+	if len(translated) != len(program) {
+		panic(fmt.Sprintf("translation has %d lines for %d instructions", len(translated), len(program)))
+	}
+	copy(program, translated)
 }
 
-func getLabel(program []string) map[string]int {
-	output := make(map[string]int)
-	for i, line := range program {
-		if strings.Contains(line, "REM") {
-			label := strings.Split(line, " ")[1]
-			output[label] = (i + 1) * 10
+// BasicOptions are the per-compilation settings of the BASIC backend.
+// Checked adds a range check after every arithmetic assignment that halts
+// with a diagnostic when the result leaves the dialect's integer range.
+// Given the origins of the intermediate instructions, Comments ends the
+// first line generated for each SPL instruction with a REM naming its line
+// in Source. SourceComments copies the comments of Source as REM lines in
+// front of the code following them.
+type BasicOptions struct {
+	Numbering      LineNumbering
+	Checked        bool
+	Comments       bool
+	SourceComments bool
+	Origins        []*parser.ASTNode
+	Source         string
+}
+
+var DefaultBasicOptions = BasicOptions{Numbering: DefaultLineNumbering}
+
+// overflowLabel marks the handler jumped to by failed range checks. It
+// cannot clash with the l<n> labels of the intermediate code.
+const overflowLabel = "overflow"
+
+// basicStatement is a translated line whose jump target, if any, is still
+// a label. The resolved target is appended to code. origin is the index of
+// the intermediate instruction it was translated from, or -1.
+type basicStatement struct {
+	code   string
+	label  string
+	jump   string
+	origin int
+}
+
+func ValidateTranslateToBasicDialect(program []string, dialect *BasicDialect, options BasicOptions) (instrs []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
 		}
-	}
-	return output
+	}()
+
+	return TranslateToBasicDialect(program, dialect, options), nil
 }
 
-func addLineNumbers(labelMap map[string]int, program []string) {
+// TranslateToBasicDialect translates the intermediate code into the given
+// dialect, numbering the lines as requested when the dialect uses line
+// numbers. Unlike TranslateToBasic the program slice is left untouched.
+func TranslateToBasicDialect(program []string, dialect *BasicDialect, options BasicOptions) []string {
+	lines, _, _ := translateBasic(program, dialect, options)
+	return lines
+}
+
+// translateBasic returns the translated lines together with the line
+// number (zero without line numbers) and originating intermediate
+// instruction (-1 for none) of each.
+func translateBasic(program []string, dialect *BasicDialect, options BasicOptions) ([]string, []int, []int) {
+	names := newBasicNames(dialect)
+	integers := dialect.Integers
+
+	operand := func(value string) string {
+		if isNumber(value) {
+			if options.Checked && !integers.contains(numberLiteral(value)) {
+				panic(fmt.Sprintf("number %s is outside the %s dialect's range of %d to %d", value, dialect.Name, integers.Min, integers.Max))
+			}
+			return value
+		}
+		if strings.HasPrefix(value, "-") {
+			return "-" + names.get(strings.TrimPrefix(value, "-"))
+		}
+		return names.get(value)
+	}
+	goTo := "GOTO "
+	then := "THEN "
+	if !dialect.LineNumbers {
+		then = "THEN GOTO "
+	}
+
+	statements := make([]basicStatement, 0, len(program))
+	for _, line := range integers.Prologue {
+		statements = append(statements, basicStatement{code: line, origin: -1})
+	}
+	var comments []lexer.Comment
+	if options.SourceComments {
+		comments = lexer.Comments(options.Source)
+	}
+	remark := func(comment lexer.Comment) {
+		for _, text := range strings.Split(comment.Body(), "\n") {
+			text = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "*"))
+			if dialect.UppercaseStrings {
+				text = strings.ToUpper(text)
+			}
+			statements = append(statements, basicStatement{code: strings.TrimSpace("REM " + text), origin: -1})
+		}
+	}
+	origin := func(i int) *parser.ASTNode {
+		if i < len(options.Origins) && options.Origins[i] != nil && !options.Origins[i].Span.IsZero() {
+			return options.Origins[i]
+		}
+		return nil
+	}
+	checks := false
 	for i, line := range program {
-		if strings.Contains(line, "GOTO") || strings.Contains(line, "THEN") {
-			tokens := strings.Split(line, " ")
-			label := tokens[len(tokens)-1]
-			ln, ok := labelMap[label]
+		// Comments go in front of the code after them, except that one
+		// following code on the same line goes after the code.
+		if node := origin(i); node != nil {
+			for len(comments) > 0 && comments[0].Offset < node.Span.Start.Offset {
+				remark(comments[0])
+				comments = comments[1:]
+			}
+		}
+		first := len(statements)
+		switch {
+		case line == "STOP":
+			statements = append(statements, basicStatement{code: dialect.Halt})
+		case strings.HasPrefix(line, `PRINT "`):
+			if dialect.UppercaseStrings {
+				line = strings.ToUpper(line)
+			}
+			statements = append(statements, basicStatement{code: line})
+		case strings.HasPrefix(line, "PRINT "):
+			statements = append(statements, basicStatement{code: "PRINT " + operand(strings.TrimPrefix(line, "PRINT "))})
+		case strings.HasPrefix(line, "REM "):
+			statements = append(statements, basicStatement{code: line, label: strings.TrimPrefix(line, "REM ")})
+		case strings.HasPrefix(line, "GOTO "):
+			statements = append(statements, basicStatement{code: goTo, jump: strings.TrimPrefix(line, "GOTO ")})
+		case strings.HasPrefix(line, "IF "):
+			tokens := strings.Fields(line)
+			if len(tokens) != 6 || tokens[4] != "THEN" {
+				panic("malformed conditional jump: " + line)
+			}
+			statements = append(statements, basicStatement{
+				code: fmt.Sprintf("IF %s %s %s %s", operand(tokens[1]), tokens[2], operand(tokens[3]), then),
+				jump: tokens[5],
+			})
+		default:
+			tokens := strings.Fields(line)
+			if len(tokens) < 3 || tokens[1] != "=" {
+				panic("unsupported intermediate instruction: " + line)
+			}
+			target := operand(tokens[0])
+			switch len(tokens) {
+			case 3:
+				statements = append(statements, basicStatement{code: fmt.Sprintf("%s = %s", target, operand(tokens[2]))})
+			case 5:
+				if tokens[3] == "/" {
+					statements = append(statements, basicStatement{code: fmt.Sprintf("%s = %s", target, fmt.Sprintf(integers.Division, operand(tokens[2]), operand(tokens[4])))})
+				} else {
+					statements = append(statements, basicStatement{code: fmt.Sprintf("%s = %s %s %s", target, operand(tokens[2]), tokens[3], operand(tokens[4]))})
+				}
+			default:
+				panic("unsupported intermediate instruction: " + line)
+			}
+			if options.Checked && (len(tokens) == 5 || strings.HasPrefix(tokens[2], "-")) {
+				checks = true
+				statements = append(statements,
+					basicStatement{code: fmt.Sprintf("IF %s > %d %s", target, integers.Max, then), jump: overflowLabel},
+					basicStatement{code: fmt.Sprintf("IF %s < %d %s", target, integers.Min, then), jump: overflowLabel})
+			}
+		}
+		for j := first; j < len(statements); j++ {
+			statements[j].origin = i
+		}
+		if node := origin(i); node != nil && origin(i+1) != node {
+			end := node.Span.End
+			for len(comments) > 0 && comments[0].Trailing && comments[0].Line == end.Line && comments[0].Offset >= end.Offset {
+				remark(comments[0])
+				comments = comments[1:]
+			}
+		}
+	}
+	if checks {
+		if statements[len(statements)-1].code != dialect.Halt {
+			statements = append(statements, basicStatement{code: dialect.Halt, origin: -1})
+		}
+		statements = append(statements,
+			basicStatement{code: "REM " + overflowLabel, label: overflowLabel, origin: -1},
+			basicStatement{code: `PRINT "INTEGER OVERFLOW"`, origin: -1},
+			basicStatement{code: dialect.Halt, origin: -1})
+	}
+	for _, comment := range comments {
+		remark(comment)
+	}
+
+	numbers := make([]int, len(statements))
+	if dialect.LineNumbers {
+		numbers = options.Numbering.assign(len(statements), dialect.MaxLine)
+	}
+	labelMap := getLabel(statements, numbers)
+
+	sourceLines := strings.Split(options.Source, "\n")
+	output := make([]string, len(statements))
+	origins := make([]int, len(statements))
+	var commented *parser.ASTNode
+	for i, statement := range statements {
+		origins[i] = statement.origin
+		line := statement.code
+		switch {
+		case statement.jump != "" && dialect.LineNumbers:
+			ln, ok := labelMap[statement.jump]
 			if !ok {
-				panic(fmt.Sprintf("label %s not found", label))
+				panic(fmt.Sprintf("label %s not found", statement.jump))
+			}
+			line += fmt.Sprint(ln)
+		case statement.jump != "":
+			if _, ok := labelMap[statement.jump]; !ok {
+				panic(fmt.Sprintf("label %s not found", statement.jump))
+			}
+			line += statement.jump
+		case statement.label != "" && !dialect.LineNumbers:
+			line = statement.label + ":"
+		}
+		if options.Comments && statement.label == "" && statement.origin >= 0 && statement.origin < len(options.Origins) {
+			if node := options.Origins[statement.origin]; node != nil && node != commented && !node.Span.IsZero() {
+				line += basicComment(node, sourceLines)
+				commented = node
 			}
-			instruction := strings.Join(tokens[:len(tokens)-1], " ")
+		}
+		if !dialect.LineNumbers && statement.label == "" {
+			line = "    " + line
+		}
+		output[i] = line
+	}
+	if dialect.LineNumbers {
+		return numberLines(output, numbers, dialect.AlignLineNumbers), numbers, origins
+	}
+	return output, numbers, origins
+}
+
+// basicComment names the SPL line an instruction starts on, followed by
+// the text of that line when the source is known.
+func basicComment(node *parser.ASTNode, sourceLines []string) string {
+	line := node.Span.Start.Line
+	comment := fmt.Sprintf(": REM SPL %d", line)
+	if line <= len(sourceLines) {
+		if text := strings.TrimSpace(sourceLines[line-1]); text != "" {
+			comment += ": " + text
+		}
+	}
+	return comment
+}
 
-			program[i] = fmt.Sprintf("%s %d", instruction, ln)
+func getLabel(statements []basicStatement, numbers []int) map[string]int {
+	output := make(map[string]int)
+	for i, statement := range statements {
+		if statement.label != "" {
+			output[statement.label] = numbers[i]
 		}
-		program[i] = fmt.Sprintf("%-3d %s", (i+1)*10, program[i])
 	}
+	return output
 }
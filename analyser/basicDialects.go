@@ -0,0 +1,203 @@
+package analyser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BasicDialect describes the flavour of BASIC the intermediate code is
+// translated into.
+type BasicDialect struct {
+	Name        string
+	Description string
+	// IdentifierLength is the number of significant characters in a
+	// variable name, zero meaning no limit. Variables that would become
+	// indistinguishable are renamed.
+	IdentifierLength int
+	// Reserved lists the keywords no variable may be called. With
+	// CrunchKeywords a variable may not contain one anywhere either, since
+	// the interpreter tokenises keywords inside names.
+	Reserved       []string
+	CrunchKeywords bool
+	Uppercase      bool
+	// LineNumbers selects numbered lines, no higher than MaxLine unless it
+	// is zero. AlignLineNumbers pads them to a common width. Without line
+	// numbers every label becomes a named line label and jumps use GOTO.
+	LineNumbers      bool
+	AlignLineNumbers bool
+	MaxLine          int
+	Integers         BasicIntegers
+	UppercaseStrings bool
+	Halt             string
+}
+
+// BasicIntegers describes how a dialect holds SPL's integers. Min and Max
+// bound the values it represents exactly, Division formats `a / b` given
+// both operands so that it truncates towards zero like SPL's div, and the
+// Prologue lines come before the program, e.g. to declare integer types.
+type BasicIntegers struct {
+	Min      int64
+	Max      int64
+	Division string
+	Prologue []string
+}
+
+func (i BasicIntegers) contains(value int64) bool {
+	return value >= i.Min && value <= i.Max
+}
+
+// floatIntegers are used by interpreters whose numbers are five byte
+// floats. They print at most nine digits before switching to exponent
+// notation, and INT rounds down, so division truncates through ABS and SGN.
+var floatIntegers = BasicIntegers{
+	Min:      -999999999,
+	Max:      999999999,
+	Division: "SGN(%[1]s) * SGN(%[2]s) * INT(ABS(%[1]s) / ABS(%[2]s))",
+}
+
+// BasicDialects holds the dialects selectable with --dialect.
+var BasicDialects = map[string]*BasicDialect{
+	"spl": {
+		Name:             "spl",
+		Description:      "the course dialect with numbered lines, 16-bit integers and STOP",
+		LineNumbers:      true,
+		AlignLineNumbers: true,
+		Integers: BasicIntegers{
+			Min:      -32768,
+			Max:      32767,
+			Division: "SGN(%[1]s) * SGN(%[2]s) * INT(ABS(%[1]s) / ABS(%[2]s))",
+		},
+		Halt: "STOP",
+	},
+	"msbasic": {
+		Name:             "msbasic",
+		Description:      "classic Microsoft BASIC with two significant characters per variable",
+		IdentifierLength: 2,
+		Reserved:         []string{"FN", "GO", "IF", "ON", "OR", "TO", "ST", "TI"},
+		CrunchKeywords:   true,
+		Uppercase:        true,
+		LineNumbers:      true,
+		MaxLine:          63999,
+		Integers:         floatIntegers,
+		Halt:             "END",
+	},
+	"applesoft": {
+		Name:             "applesoft",
+		Description:      "Applesoft BASIC for the Apple II, upper case only",
+		IdentifierLength: 2,
+		Reserved:         []string{"AT", "FN", "GO", "GR", "IF", "ON", "OR", "TO"},
+		CrunchKeywords:   true,
+		Uppercase:        true,
+		LineNumbers:      true,
+		MaxLine:          63999,
+		Integers:         floatIntegers,
+		UppercaseStrings: true,
+		Halt:             "END",
+	},
+	"qbasic": {
+		Name:        "qbasic",
+		Description: "QBasic style without line numbers, using LONG variables and integer division",
+		Reserved:    []string{"AS", "DO", "FN", "IF", "IS", "ON", "OR", "TO"},
+		Integers: BasicIntegers{
+			Min:      -2147483648,
+			Max:      2147483647,
+			Division: `%s \ %s`,
+			Prologue: []string{"DEFLNG A-Z"},
+		},
+		Halt: "END",
+	},
+}
+
+// LookupBasicDialect returns the dialect with the given name.
+func LookupBasicDialect(name string) (*BasicDialect, error) {
+	dialect, ok := BasicDialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown BASIC dialect %q (available: %v)", name, BasicDialectNames())
+	}
+	return dialect, nil
+}
+
+// BasicDialectNames returns the dialect names in sorted order.
+func BasicDialectNames() []string {
+	names := make([]string, 0, len(BasicDialects))
+	for name := range BasicDialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// basicNames maps intermediate variables to names that are valid and
+// distinct in a dialect. A variable keeps its own name (cased and
+// truncated) when possible and otherwise gets the first free one.
+type basicNames struct {
+	dialect *BasicDialect
+	names   map[string]string
+	taken   map[string]bool
+}
+
+func newBasicNames(dialect *BasicDialect) *basicNames {
+	return &basicNames{
+		dialect: dialect,
+		names:   make(map[string]string),
+		taken:   make(map[string]bool),
+	}
+}
+
+func (n *basicNames) get(variable string) string {
+	if name, ok := n.names[variable]; ok {
+		return name
+	}
+	name := n.spell(variable)
+	if !n.allowed(name) {
+		name = n.fresh()
+	}
+	n.names[variable] = name
+	n.taken[strings.ToUpper(name)] = true
+	return name
+}
+
+func (n *basicNames) spell(name string) string {
+	if n.dialect.IdentifierLength > 0 && len(name) > n.dialect.IdentifierLength {
+		name = name[:n.dialect.IdentifierLength]
+	}
+	if n.dialect.Uppercase {
+		name = strings.ToUpper(name)
+	}
+	return name
+}
+
+func (n *basicNames) allowed(name string) bool {
+	upper := strings.ToUpper(name)
+	if n.taken[upper] {
+		return false
+	}
+	for _, keyword := range n.dialect.Reserved {
+		if upper == keyword || n.dialect.CrunchKeywords && strings.Contains(upper, keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *basicNames) fresh() string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	candidates := make([]string, 0)
+	for _, first := range letters {
+		candidates = append(candidates, string(first))
+	}
+	if n.dialect.IdentifierLength != 1 {
+		for _, first := range letters {
+			for _, second := range letters + "0123456789" {
+				candidates = append(candidates, string(first)+string(second))
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		if name := n.spell(candidate); n.allowed(name) {
+			return name
+		}
+	}
+	panic(fmt.Sprintf("run out of variable names for the %s dialect", n.dialect.Name))
+}
@@ -51,3 +51,292 @@ func testBasic(ast *parser.ASTNode) {
 	TranslateToBasic(lines)
 	fmt.Println(strings.Join(lines, "\n"))
 }
+
+func TestTranslateToBasicDialect(t *testing.T) {
+	program := []string{
+		"REM l0",
+		"to = 7",
+		"a1 = -to",
+		"b = a1 / 2",
+		"IF b = 0 THEN l1",
+		"PRINT b",
+		"GOTO l0",
+		"REM l1",
+		`PRINT "done"`,
+		"STOP",
+	}
+	tests := []struct {
+		dialect  string
+		expected []string
+	}{
+		{"spl", []string{
+			"10  REM l0",
+			"20  to = 7",
+			"30  a1 = -to",
+			"40  b = SGN(a1) * SGN(2) * INT(ABS(a1) / ABS(2))",
+			"50  IF b = 0 THEN 80",
+			"60  PRINT b",
+			"70  GOTO 10",
+			"80  REM l1",
+			`90  PRINT "done"`,
+			"100 STOP",
+		}},
+		{"msbasic", []string{
+			"10 REM l0",
+			"20 A = 7",
+			"30 A1 = -A",
+			"40 B = SGN(A1) * SGN(2) * INT(ABS(A1) / ABS(2))",
+			"50 IF B = 0 THEN 80",
+			"60 PRINT B",
+			"70 GOTO 10",
+			"80 REM l1",
+			`90 PRINT "done"`,
+			"100 END",
+		}},
+		{"applesoft", []string{
+			"10 REM l0",
+			"20 A = 7",
+			"30 A1 = -A",
+			"40 B = SGN(A1) * SGN(2) * INT(ABS(A1) / ABS(2))",
+			"50 IF B = 0 THEN 80",
+			"60 PRINT B",
+			"70 GOTO 10",
+			"80 REM l1",
+			`90 PRINT "DONE"`,
+			"100 END",
+		}},
+		{"qbasic", []string{
+			"    DEFLNG A-Z",
+			"l0:",
+			"    a = 7",
+			"    a1 = -a",
+			`    b = a1 \ 2`,
+			"    IF b = 0 THEN GOTO l1",
+			"    PRINT b",
+			"    GOTO l0",
+			"l1:",
+			`    PRINT "done"`,
+			"    END",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			dialect, err := LookupBasicDialect(tt.dialect)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := ValidateTranslateToBasicDialect(program, dialect, DefaultBasicOptions)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("got:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+
+	if _, err := LookupBasicDialect("cobol"); err == nil {
+		t.Errorf("expected an error for an unknown dialect")
+	}
+	if _, err := ValidateTranslateToBasicDialect([]string{"GOTO l9"}, BasicDialects["spl"], DefaultBasicOptions); err == nil {
+		t.Errorf("expected an error for a missing label")
+	}
+}
+
+func TestLineNumbering(t *testing.T) {
+	program := []string{"REM l0", "a = 1", "GOTO l0"}
+	tests := []struct {
+		name      string
+		dialect   string
+		numbering LineNumbering
+		expected  []string
+		err       string
+	}{
+		{"Testing start and increment", "msbasic", LineNumbering{Start: 100, Increment: 5},
+			[]string{"100 REM l0", "105 A = 1", "110 GOTO 100"}, ""},
+		{"Testing alignment past three digits", "spl", LineNumbering{Start: 990, Increment: 5},
+			[]string{"990  REM l0", "995  a = 1", "1000 GOTO 990"}, ""},
+		{"Testing the maximum line number", "spl", LineNumbering{Start: 10, Increment: 10, Max: 25},
+			nil, "line number 30 exceeds the limit of 25"},
+		{"Testing the dialect line limit", "applesoft", LineNumbering{Start: 63990, Increment: 10},
+			nil, "line number 64000 exceeds the limit of 63999"},
+		{"Testing a zero increment", "spl", LineNumbering{Start: 10},
+			nil, "line increment 0 must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateTranslateToBasicDialect(program, BasicDialects[tt.dialect], BasicOptions{Numbering: tt.numbering})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("got:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestRenumberBasic(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+		err      string
+	}{
+		{"Testing jumps, lists and literals", []string{
+			"5 REM GOTO 5 stays",
+			"7 IF A > 0 THEN 30 ELSE 9",
+			"",
+			"9 PRINT \"GOTO 7\": GOSUB 30",
+			"30 ON A GOTO 5, 7,9",
+		}, []string{
+			"100 REM GOTO 5 stays",
+			"110 IF A > 0 THEN 130 ELSE 120",
+			"120 PRINT \"GOTO 7\": GOSUB 130",
+			"130 ON A GOTO 100, 110,120",
+		}, ""},
+		{"Testing keywords inside names", []string{
+			"10 PREMIUM = 1: GOTO 20",
+			"20 IF NOTHEN > ELSEWHERE THEN 10",
+			"30 GOTO20",
+		}, []string{
+			"100 PREMIUM = 1: GOTO 110",
+			"110 IF NOTHEN > ELSEWHERE THEN 100",
+			"120 GOTO110",
+		}, ""},
+		{"Testing a missing target", []string{"10 GOTO 20"}, nil, "line 10: GOTO to line 20 which does not exist"},
+		{"Testing lines out of order", []string{"20 END", "10 END"}, nil, "line 10 does not come after line 20"},
+		{"Testing a line without a number", []string{"PRINT 1"}, nil, "line without a line number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateRenumberBasic(tt.input, LineNumbering{Start: 100, Increment: 10}, false)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("got:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckedBasic(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  string
+		program  []string
+		expected []string
+		err      string
+	}{
+		{"Testing checks after arithmetic and negation", "spl",
+			[]string{"a = 30000", "b = a + a", "c = -b", "d = c", "PRINT d", "STOP"},
+			[]string{
+				"10  a = 30000",
+				"20  b = a + a",
+				"30  IF b > 32767 THEN 110",
+				"40  IF b < -32768 THEN 110",
+				"50  c = -b",
+				"60  IF c > 32767 THEN 110",
+				"70  IF c < -32768 THEN 110",
+				"80  d = c",
+				"90  PRINT d",
+				"100 STOP",
+				"110 REM overflow",
+				`120 PRINT "INTEGER OVERFLOW"`,
+				"130 STOP",
+			}, ""},
+		{"Testing a program without a final halt", "qbasic",
+			[]string{"a = 7 / 2"},
+			[]string{
+				"    DEFLNG A-Z",
+				`    a = 7 \ 2`,
+				"    IF a > 2147483647 THEN GOTO overflow",
+				"    IF a < -2147483648 THEN GOTO overflow",
+				"    END",
+				"overflow:",
+				`    PRINT "INTEGER OVERFLOW"`,
+				"    END",
+			}, ""},
+		{"Testing a literal outside the range", "spl",
+			[]string{"a = 40000"}, nil, "number 40000 is outside the spl dialect's range of -32768 to 32767"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := BasicOptions{Numbering: DefaultLineNumbering, Checked: true}
+			got, err := ValidateTranslateToBasicDialect(tt.program, BasicDialects[tt.dialect], options)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("got:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestSourceCommentsBasic(t *testing.T) {
+	input := `glob { }
+proc { }
+func { }
+main {
+	var { x }
+	// start at two
+	x = 2;
+	/* count
+	   down */
+	while (x > 0) {
+		x = (x minus 1) // step
+	}
+}
+// done`
+	root := analyseForTest(t, input)
+	program, origins := GenerateIntermediate(root)
+	options := BasicOptions{Numbering: DefaultLineNumbering, SourceComments: true, Origins: origins, Source: input}
+	got, err := ValidateTranslateToBasicDialect(program, BasicDialects["spl"], options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"10  REM start at two",
+		"20  aa = 2",
+		"30  a = aa",
+		"40  REM count",
+		"50  REM down",
+		"60  REM l0",
+		"70  ab = a",
+		"80  ac = 0",
+		"90  IF ab > ac THEN 110",
+		"100 GOTO 180",
+		"110 REM l1",
+		"120 ae = a",
+		"130 af = 1",
+		"140 ad = ae - af",
+		"150 a = ad",
+		"160 REM step",
+		"170 GOTO 60",
+		"180 REM l2",
+		"190 REM done",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
@@ -0,0 +1,255 @@
+package analyser
+
+import (
+	"SPL-compiler/bytecode"
+	"SPL-compiler/parser"
+	"fmt"
+)
+
+type bytecodeCompiler struct {
+	program   *bytecode.Program
+	globalIdx map[string]int
+	funcIdx   map[string]int
+	localIdx  map[string]int
+	strings   map[string]int
+	code      []bytecode.Instruction
+}
+
+func ValidateCompileToBytecode(root *parser.ASTNode) (program *bytecode.Program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return CompileToBytecode(root), nil
+}
+
+// CompileToBytecode compiles an analysed SPL program for the bytecode VM.
+// Procedures and functions keep their own frames, so unlike the
+// intermediate code nothing is inlined. The main program is the last
+// function and the entry point.
+func CompileToBytecode(root *parser.ASTNode) *bytecode.Program {
+	c := &bytecodeCompiler{
+		program:   &bytecode.Program{},
+		globalIdx: make(map[string]int),
+		funcIdx:   make(map[string]int),
+		strings:   make(map[string]int),
+	}
+
+	for _, v := range root.Children[0].Children {
+		c.globalIdx[getVar(v)] = c.program.Globals
+		c.program.Globals++
+	}
+
+	defs := definitions(root)
+	for i, def := range defs {
+		c.funcIdx[symbolTable[int(def.Children[0].ID)].uniqueID] = i
+	}
+
+	for _, def := range defs {
+		params := parameterList(def)
+		locals := localList(def)
+		c.enter(append(append([]*parser.ASTNode{}, params...), locals...))
+		c.algo(def.Children[2].Children[1])
+		if def.Type == FDEF {
+			c.atom(def.Children[3])
+		}
+		c.emit(bytecode.OpReturn, 0)
+		c.program.Functions = append(c.program.Functions, bytecode.Function{
+			Name:    def.Children[0].Name,
+			Params:  len(params),
+			Locals:  len(locals),
+			Returns: def.Type == FDEF,
+			Code:    c.code,
+		})
+	}
+
+	mainProg := root.Children[3]
+	mainVars := mainProg.Children[0].Children
+	c.enter(mainVars)
+	c.algo(mainProg.Children[1])
+	c.emit(bytecode.OpHalt, 0)
+	c.program.Entry = len(c.program.Functions)
+	c.program.Functions = append(c.program.Functions, bytecode.Function{
+		Name:   "main",
+		Locals: len(mainVars),
+		Code:   c.code,
+	})
+
+	return c.program
+}
+
+func (c *bytecodeCompiler) enter(vars []*parser.ASTNode) {
+	c.code = make([]bytecode.Instruction, 0)
+	c.localIdx = make(map[string]int)
+	for i, v := range vars {
+		c.localIdx[getVar(v)] = i
+	}
+}
+
+// emit appends an instruction and returns its address for back-patching.
+func (c *bytecodeCompiler) emit(op bytecode.Opcode, arg int64) int {
+	c.code = append(c.code, bytecode.Instruction{Op: op, Arg: arg})
+	return len(c.code) - 1
+}
+
+func (c *bytecodeCompiler) patch(address int) {
+	c.code[address].Arg = int64(len(c.code))
+}
+
+func (c *bytecodeCompiler) algo(node *parser.ASTNode) {
+	for _, instr := range node.Children {
+		c.instr(instr)
+	}
+}
+
+func (c *bytecodeCompiler) instr(node *parser.ASTNode) {
+	switch node.Name {
+	case "halt":
+		c.emit(bytecode.OpHalt, 0)
+	case "print":
+		output := node.Children[0]
+		if len(output.Children) > 0 {
+			c.atom(output.Children[0])
+			c.emit(bytecode.OpPrint, 0)
+			return
+		}
+		index, ok := c.strings[output.Name]
+		if !ok {
+			index = len(c.program.Strings)
+			c.strings[output.Name] = index
+			c.program.Strings = append(c.program.Strings, output.Name)
+		}
+		c.emit(bytecode.OpPrintString, int64(index))
+	case "call":
+		c.call(node.Children[0], node.Children[1])
+	case "assign":
+		assign := node.Children[0]
+		if assign.Name == "call" {
+			c.call(assign.Children[1], assign.Children[2])
+		} else {
+			c.term(assign.Children[1])
+		}
+		c.store(getVar(assign.Children[0]))
+	case "loop":
+		c.loop(node.Children[0])
+	case "branch":
+		c.branch(node.Children[0])
+	default:
+		panic("expected 'halt', 'print', 'call', 'assign', 'loop' or 'branch' Instr node name")
+	}
+}
+
+func (c *bytecodeCompiler) call(name, input *parser.ASTNode) {
+	for _, atom := range input.Children {
+		c.atom(atom)
+	}
+	c.emit(bytecode.OpCall, int64(c.funcIdx[declaredUniqueID(name)]))
+}
+
+func (c *bytecodeCompiler) loop(node *parser.ASTNode) {
+	switch node.Name {
+	case "while":
+		start := len(c.code)
+		c.term(node.Children[0])
+		exit := c.emit(bytecode.OpJumpIfFalse, 0)
+		c.algo(node.Children[1])
+		c.emit(bytecode.OpJump, int64(start))
+		c.patch(exit)
+	case "do":
+		start := len(c.code)
+		c.algo(node.Children[0])
+		c.term(node.Children[1])
+		c.emit(bytecode.OpJumpIfFalse, int64(start))
+	default:
+		panic("expected 'while' or 'do' Loop node name")
+	}
+}
+
+func (c *bytecodeCompiler) branch(node *parser.ASTNode) {
+	c.term(node.Children[0])
+	skip := c.emit(bytecode.OpJumpIfFalse, 0)
+	c.algo(node.Children[1])
+	switch node.Name {
+	case "if":
+		c.patch(skip)
+	case "ifelse":
+		exit := c.emit(bytecode.OpJump, 0)
+		c.patch(skip)
+		c.algo(node.Children[2])
+		c.patch(exit)
+	default:
+		panic("expected 'if' or 'ifelse' Branch node name")
+	}
+}
+
+func (c *bytecodeCompiler) term(node *parser.ASTNode) {
+	switch node.Name {
+	case "atom":
+		c.atom(node.Children[0])
+	case "unop":
+		c.term(node.Children[1])
+		switch node.Children[0].Name {
+		case "neg":
+			c.emit(bytecode.OpNeg, 0)
+		case "not":
+			c.emit(bytecode.OpNot, 0)
+		default:
+			panic("expected 'neg' or 'not' UnOp node name")
+		}
+	case "binop":
+		c.term(node.Children[0])
+		c.term(node.Children[2])
+		c.emit(bytecodeBinOp(node.Children[1]), 0)
+	default:
+		panic("expected 'atom', 'unop', or 'binop' Term node name")
+	}
+}
+
+func bytecodeBinOp(node *parser.ASTNode) bytecode.Opcode {
+	switch node.Name {
+	case "eq":
+		return bytecode.OpEq
+	case ">":
+		return bytecode.OpGt
+	case "or":
+		return bytecode.OpOr
+	case "and":
+		return bytecode.OpAnd
+	case "plus":
+		return bytecode.OpAdd
+	case "minus":
+		return bytecode.OpSub
+	case "mult":
+		return bytecode.OpMul
+	case "div":
+		return bytecode.OpDiv
+	default:
+		panic("expected 'eq', '>', 'or', 'and', 'plus', 'minus', 'mult', or 'div' BinOp node name")
+	}
+}
+
+func (c *bytecodeCompiler) atom(node *parser.ASTNode) {
+	if len(node.Children) > 0 {
+		c.load(getVar(node.Children[0]))
+		return
+	}
+	c.emit(bytecode.OpConst, numberLiteral(node.Name))
+}
+
+func (c *bytecodeCompiler) load(name string) {
+	if idx, ok := c.localIdx[name]; ok {
+		c.emit(bytecode.OpLoadLocal, int64(idx))
+		return
+	}
+	c.emit(bytecode.OpLoadGlobal, int64(c.globalIdx[name]))
+}
+
+func (c *bytecodeCompiler) store(name string) {
+	if idx, ok := c.localIdx[name]; ok {
+		c.emit(bytecode.OpStoreLocal, int64(idx))
+		return
+	}
+	c.emit(bytecode.OpStoreGlobal, int64(c.globalIdx[name]))
+}
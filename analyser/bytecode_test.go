@@ -0,0 +1,40 @@
+package analyser
+
+import (
+	"bytes"
+	"testing"
+
+	"SPL-compiler/bytecode"
+)
+
+func TestCompileToBytecode(t *testing.T) {
+	for _, tt := range backendPrograms {
+		t.Run(tt.name, func(t *testing.T) {
+			root := analyseForTest(t, tt.input)
+			program, err := ValidateCompileToBytecode(root)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			decoded, err := bytecode.Decode(bytecode.Encode(program))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if decoded.Disassemble() != program.Disassemble() {
+				t.Fatalf("program changed in the .splc round trip:\n%s\n%s",
+					program.Disassemble(), decoded.Disassemble())
+			}
+
+			var out bytes.Buffer
+			vm := &bytecode.VM{Stdout: &out, MaxSteps: 100000}
+			if err := vm.Run(decoded); err != nil {
+				t.Fatalf("run: %v\n%s", err, program.Disassemble())
+			}
+			if out.String() != tt.expected {
+				t.Errorf("expected output %q, got %q\n%s", tt.expected, out.String(), program.Disassemble())
+			}
+			if vm.Steps == 0 {
+				t.Errorf("expected instructions to be counted")
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+	"strings"
+)
+
+func ValidateTranslateToC(root *parser.ASTNode) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return TranslateToC(root), nil
+}
+
+// TranslateToC emits a standalone C99 program for an analysed SPL program.
+// Globals become file scope variables, procedures and functions become C
+// functions and main variables become locals of main. Every identifier is
+// the uniqueID from the symbol table, so AnalyseProgram must have run first.
+func TranslateToC(root *parser.ASTNode) string {
+	procs := root.Children[1].Children
+	funcs := root.Children[2].Children
+
+	output := []string{
+		"#include <stdio.h>",
+		"#include <stdlib.h>",
+		"",
+	}
+
+	globals := root.Children[0].Children
+	for _, v := range globals {
+		output = append(output, fmt.Sprintf("long long %s = 0; /* %s */", getVar(v), v.Name))
+	}
+	if len(globals) > 0 {
+		output = append(output, "")
+	}
+
+	for _, def := range procs {
+		output = append(output, cSignature(def, "void")+";")
+	}
+	for _, def := range funcs {
+		output = append(output, cSignature(def, "long long")+";")
+	}
+	if len(procs)+len(funcs) > 0 {
+		output = append(output, "")
+	}
+
+	for _, def := range procs {
+		output = append(output, fmt.Sprintf("/* proc %s */", def.Children[0].Name))
+		output = append(output, cSignature(def, "void")+" {")
+		output = append(output, cLocals(localList(def), 1)...)
+		output = append(output, cAlgo(def.Children[2].Children[1], 1)...)
+		output = append(output, "}", "")
+	}
+	for _, def := range funcs {
+		output = append(output, fmt.Sprintf("/* func %s */", def.Children[0].Name))
+		output = append(output, cSignature(def, "long long")+" {")
+		output = append(output, cLocals(localList(def), 1)...)
+		output = append(output, cAlgo(def.Children[2].Children[1], 1)...)
+		output = append(output, fmt.Sprintf("\treturn %s;", getAtom(def.Children[3])))
+		output = append(output, "}", "")
+	}
+
+	mainProg := root.Children[3]
+	output = append(output, "int main(void) {")
+	output = append(output, cLocals(mainProg.Children[0].Children, 1)...)
+	output = append(output, cAlgo(mainProg.Children[1], 1)...)
+	output = append(output, "\treturn 0;", "}", "")
+
+	return strings.Join(output, "\n")
+}
+
+func cSignature(def *parser.ASTNode, returnType string) string {
+	params := make([]string, 0)
+	for _, param := range parameterList(def) {
+		params = append(params, "long long "+getVar(param))
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+	name := symbolTable[int(def.Children[0].ID)].uniqueID
+	return fmt.Sprintf("static %s %s(%s)", returnType, name, strings.Join(params, ", "))
+}
+
+func cLocals(vars []*parser.ASTNode, depth int) []string {
+	output := make([]string, 0)
+	for _, v := range vars {
+		output = append(output, fmt.Sprintf("%slong long %s = 0; /* %s */", cIndent(depth), getVar(v), v.Name))
+	}
+	return output
+}
+
+func cIndent(depth int) string {
+	return strings.Repeat("\t", depth)
+}
+
+func cAlgo(node *parser.ASTNode, depth int) []string {
+	output := make([]string, 0)
+	for _, instr := range node.Children {
+		output = append(output, cInstr(instr, depth)...)
+	}
+	return output
+}
+
+func cInstr(node *parser.ASTNode, depth int) []string {
+	indent := cIndent(depth)
+	switch node.Name {
+	case "halt":
+		return []string{indent + "exit(0);"}
+	case "print":
+		output := node.Children[0]
+		if len(output.Children) > 0 {
+			return []string{fmt.Sprintf(`%sprintf("%%lld\n", %s);`, indent, getAtom(output.Children[0]))}
+		}
+		return []string{fmt.Sprintf("%sputs(%s);", indent, cString(output.Name))}
+	case "call":
+		name := declaredUniqueID(node.Children[0])
+		return []string{fmt.Sprintf("%s%s(%s);", indent, name, cArgs(node.Children[1]))}
+	case "assign":
+		return []string{indent + cAssign(node.Children[0])}
+	case "loop":
+		return cLoop(node.Children[0], depth)
+	case "branch":
+		return cBranch(node.Children[0], depth)
+	default:
+		panic("expected 'halt', 'print', 'call', 'assign', 'loop' or 'branch' Instr node name")
+	}
+}
+
+func cArgs(input *parser.ASTNode) string {
+	args := make([]string, 0)
+	for _, atom := range input.Children {
+		args = append(args, getAtom(atom))
+	}
+	return strings.Join(args, ", ")
+}
+
+func cAssign(node *parser.ASTNode) string {
+	vname := getVar(node.Children[0])
+	if node.Name == "call" {
+		name := declaredUniqueID(node.Children[1])
+		return fmt.Sprintf("%s = %s(%s);", vname, name, cArgs(node.Children[2]))
+	}
+	return fmt.Sprintf("%s = %s;", vname, cTerm(node.Children[1]))
+}
+
+func cLoop(node *parser.ASTNode, depth int) []string {
+	indent := cIndent(depth)
+	switch node.Name {
+	case "while":
+		output := []string{fmt.Sprintf("%swhile (%s) {", indent, cTerm(node.Children[0]))}
+		output = append(output, cAlgo(node.Children[1], depth+1)...)
+		return append(output, indent+"}")
+	case "do":
+		output := []string{indent + "do {"}
+		output = append(output, cAlgo(node.Children[0], depth+1)...)
+		return append(output, fmt.Sprintf("%s} while (!%s);", indent, cTerm(node.Children[1])))
+	default:
+		panic("expected 'while' or 'do' Loop node name")
+	}
+}
+
+func cBranch(node *parser.ASTNode, depth int) []string {
+	indent := cIndent(depth)
+	output := []string{fmt.Sprintf("%sif (%s) {", indent, cTerm(node.Children[0]))}
+	output = append(output, cAlgo(node.Children[1], depth+1)...)
+	switch node.Name {
+	case "if":
+		return append(output, indent+"}")
+	case "ifelse":
+		output = append(output, indent+"} else {")
+		output = append(output, cAlgo(node.Children[2], depth+1)...)
+		return append(output, indent+"}")
+	default:
+		panic("expected 'if' or 'ifelse' Branch node name")
+	}
+}
+
+func cTerm(node *parser.ASTNode) string {
+	switch node.Name {
+	case "atom":
+		return getAtom(node.Children[0])
+	case "unop":
+		switch node.Children[0].Name {
+		case "neg":
+			return fmt.Sprintf("(-%s)", cTerm(node.Children[1]))
+		case "not":
+			return fmt.Sprintf("(!%s)", cTerm(node.Children[1]))
+		default:
+			panic("expected 'neg' or 'not' UnOp node name")
+		}
+	case "binop":
+		return fmt.Sprintf("(%s %s %s)",
+			cTerm(node.Children[0]), cBinOp(node.Children[1]), cTerm(node.Children[2]))
+	default:
+		panic("expected 'atom', 'unop', or 'binop' Term node name")
+	}
+}
+
+func cBinOp(node *parser.ASTNode) string {
+	switch node.Name {
+	case "eq":
+		return "=="
+	case ">":
+		return ">"
+	case "or":
+		return "||"
+	case "and":
+		return "&&"
+	case "plus":
+		return "+"
+	case "minus":
+		return "-"
+	case "mult":
+		return "*"
+	case "div":
+		return "/"
+	default:
+		panic("expected 'eq', '>', 'or', 'and', 'plus', 'minus', 'mult', or 'div' BinOp node name")
+	}
+}
+
+var cEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "?", `\?`)
+
+func cString(s string) string {
+	return `"` + cEscaper.Replace(s) + `"`
+}
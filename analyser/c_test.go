@@ -0,0 +1,124 @@
+package analyser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"SPL-compiler/parser"
+)
+
+// backendPrograms are shared by the backend tests; every backend must make
+// the program print exactly the expected output.
+var backendPrograms = []struct {
+	name     string
+	input    string
+	expected string
+}{
+	{"Testing countdown loop", `
+	glob { }
+	proc { }
+	func { }
+	main {
+		var { x }
+		x = 3;
+		while (x > 0) {
+			print x;
+			x = (x minus 1)
+		};
+		print "done"
+	}`, "3\n2\n1\ndone\n"},
+	{"Testing procedures, functions and globals", `
+	glob { total }
+	proc {
+		add(n) { local { } total = (total plus n) }
+	}
+	func {
+		square(n) { local { r } r = (n mult n); return r }
+	}
+	main {
+		var { x y }
+		x = square(4);
+		add(x);
+		add(3);
+		print total;
+		y = (total div 4);
+		print y;
+		y = ((neg 7) div 2);
+		print y
+	}`, "19\n4\n-3\n"},
+	{"Testing branches, do-until and halt", `
+	glob { }
+	proc { }
+	func { }
+	main {
+		var { a b }
+		a = 0;
+		b = 5;
+		do {
+			a = (a plus 1)
+		} until ((a eq b) or (a > 10));
+		if ((a eq 5) and (not (b > 5))) {
+			print "yes"
+		} else {
+			print "no"
+		};
+		if (a > 100) {
+			print "big"
+		};
+		halt;
+		print "unreachable"
+	}`, "yes\n"},
+}
+
+func analyseForTest(t *testing.T, input string) *parser.ASTNode {
+	t.Helper()
+	root, err := parser.Validate(input)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := ValidateScoping(root); err != nil {
+		t.Fatalf("scoping: %v", err)
+	}
+	if err := ValidateTypeChecking(root); err != nil {
+		t.Fatalf("type checking: %v", err)
+	}
+	if err := ValidateNoRecursion(root); err != nil {
+		t.Fatalf("recursion: %v", err)
+	}
+	return root
+}
+
+func TestTranslateToC(t *testing.T) {
+	gcc, err := exec.LookPath("gcc")
+	if err != nil {
+		t.Skip("gcc not available")
+	}
+	for _, tt := range backendPrograms {
+		t.Run(tt.name, func(t *testing.T) {
+			root := analyseForTest(t, tt.input)
+			code, err := ValidateTranslateToC(root)
+			if err != nil {
+				t.Fatalf("translate: %v", err)
+			}
+
+			dir := t.TempDir()
+			source := filepath.Join(dir, "prog.c")
+			binary := filepath.Join(dir, "prog")
+			if err := os.WriteFile(source, []byte(code), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if out, err := exec.Command(gcc, "-std=c99", "-Wall", "-Werror", "-o", binary, source).CombinedOutput(); err != nil {
+				t.Fatalf("gcc: %v\n%s\n%s", err, out, code)
+			}
+			out, err := exec.Command(binary).Output()
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if string(out) != tt.expected {
+				t.Errorf("expected output %q, got %q\n%s", tt.expected, out, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+)
+
+// CallGraph records which procedures and functions each part of a program
+// calls. Callers are the PDEF and FDEF nodes in declaration order followed
+// by the MAINPROG node; Calls lists the callees of each in call order, once
+// per call site.
+type CallGraph struct {
+	Callers []*parser.ASTNode
+	Calls   map[*parser.ASTNode][]*parser.ASTNode
+}
+
+// BuildCallGraph builds the call graph of an analysed program, including
+// calls nested in loops and branches. CheckRecursion looks for cycles in
+// it.
+func BuildCallGraph(root *parser.ASTNode) *CallGraph {
+	graph := &CallGraph{Calls: make(map[*parser.ASTNode][]*parser.ASTNode)}
+	defs := make(map[string]*parser.ASTNode)
+	for _, def := range definitions(root) {
+		defs[symbolTable[int(def.Children[0].ID)].uniqueID] = def
+		graph.Callers = append(graph.Callers, def)
+	}
+	graph.Callers = append(graph.Callers, root.Children[3])
+
+	for _, caller := range graph.Callers {
+		algo := caller.Children[1]
+		if caller.Type != MAINPROG {
+			algo = caller.Children[2].Children[1]
+		}
+		callees := make([]*parser.ASTNode, 0)
+		forEachCall(algo, func(name *parser.ASTNode) {
+			if callee, ok := defs[declaredUniqueID(name)]; ok {
+				callees = append(callees, callee)
+			}
+		})
+		graph.Calls[caller] = callees
+	}
+	return graph
+}
+
+// Cyclic reports whether a cycle of calls can be reached from caller.
+func (g *CallGraph) Cyclic(caller *parser.ASTNode) bool {
+	// A caller is on the path while its callees are searched, and done
+	// once none of them leads to a cycle.
+	onPath := make(map[*parser.ASTNode]bool)
+	done := make(map[*parser.ASTNode]bool)
+	var search func(node *parser.ASTNode) bool
+	search = func(node *parser.ASTNode) bool {
+		if onPath[node] {
+			return true
+		}
+		if done[node] {
+			return false
+		}
+		onPath[node] = true
+		for _, callee := range g.Calls[node] {
+			if search(callee) {
+				return true
+			}
+		}
+		onPath[node] = false
+		done[node] = true
+		return false
+	}
+	return search(caller)
+}
+
+// forEachCall calls visit with the NAME node of every call in an ALGO.
+func forEachCall(algo *parser.ASTNode, visit func(name *parser.ASTNode)) {
+	parser.Inspect(algo, func(node *parser.ASTNode) bool {
+		switch {
+		case node.Type == INSTR && node.Name == "call":
+			visit(node.Children[0])
+		case node.Type == ASSIGN && node.Name == "call":
+			visit(node.Children[1])
+		}
+		return true
+	})
+}
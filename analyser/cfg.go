@@ -0,0 +1,65 @@
+package analyser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BasicBlock is a maximal run of intermediate instructions entered only at
+// the top. Successors index the graph; ExitBlock stands for leaving the
+// program.
+type BasicBlock struct {
+	Start      int
+	Lines      []string
+	Successors []int
+}
+
+const ExitBlock = -1
+
+// ControlFlowGraph splits intermediate code into basic blocks. Labels start
+// a block, and jumps and STOP end one.
+func ControlFlowGraph(program []string) []BasicBlock {
+	blocks := make([]BasicBlock, 0)
+	labels := make(map[string]int)
+	for i, line := range program {
+		if i == 0 || strings.HasPrefix(line, "REM ") || endsBlock(program[i-1]) {
+			blocks = append(blocks, BasicBlock{Start: i})
+		}
+		current := &blocks[len(blocks)-1]
+		current.Lines = append(current.Lines, line)
+		if label, ok := strings.CutPrefix(line, "REM "); ok {
+			labels[label] = len(blocks) - 1
+		}
+	}
+
+	target := func(label string) int {
+		index, ok := labels[label]
+		if !ok {
+			panic(fmt.Sprintf("label %s not found", label))
+		}
+		return index
+	}
+	for i := range blocks {
+		next := i + 1
+		if next == len(blocks) {
+			next = ExitBlock
+		}
+		last := blocks[i].Lines[len(blocks[i].Lines)-1]
+		switch {
+		case last == "STOP":
+			blocks[i].Successors = []int{ExitBlock}
+		case strings.HasPrefix(last, "GOTO "):
+			blocks[i].Successors = []int{target(strings.TrimPrefix(last, "GOTO "))}
+		case strings.HasPrefix(last, "IF "):
+			tokens := strings.Fields(last)
+			blocks[i].Successors = []int{target(tokens[len(tokens)-1]), next}
+		default:
+			blocks[i].Successors = []int{next}
+		}
+	}
+	return blocks
+}
+
+func endsBlock(line string) bool {
+	return line == "STOP" || strings.HasPrefix(line, "GOTO ") || strings.HasPrefix(line, "IF ")
+}
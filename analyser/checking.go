@@ -2,12 +2,13 @@ package analyser
 
 import (
 	"SPL-compiler/parser"
+	"fmt"
 )
 
 func ValidateTypeChecking(root *parser.ASTNode) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = r.(error)
+			err = fmt.Errorf("%v", r)
 		}
 	}()
 
@@ -23,6 +24,7 @@ func checkNode(node *parser.ASTNode) {
 	if node == nil {
 		return
 	}
+	visiting = node
 
 	switch node.Type {
 	case SPL_PROG:
@@ -83,32 +85,22 @@ func checkProgram(node *parser.ASTNode) {
 }
 
 func checkVariables(node *parser.ASTNode) {
-	if len(node.Children) > 0 {
-		n := checkVar(node.Children[0])
-		if n != "numeric" {
+	for _, child := range node.Children {
+		if n := checkVar(child); n != "numeric" {
 			panic("expected numeric type for variable")
 		}
-		checkNode(node.Children[1]) // VARIABLES
-	} else {
-		return
 	}
 }
 
 func checkProcDefs(node *parser.ASTNode) {
-	if len(node.Children) > 0 {
-		checkNode(node.Children[0]) // PDEF
-		checkNode(node.Children[1]) // PROCDEFS
-	} else {
-		return
+	for _, child := range node.Children {
+		checkNode(child) // PDEF
 	}
 }
 
 func checkFuncDefs(node *parser.ASTNode) {
-	if len(node.Children) > 0 {
-		checkNode(node.Children[0]) // FDEF
-		checkNode(node.Children[1]) // FUNCDEFS
-	} else {
-		return
+	for _, child := range node.Children {
+		checkNode(child) // FDEF
 	}
 }
 
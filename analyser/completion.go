@@ -0,0 +1,295 @@
+package analyser
+
+import (
+	"SPL-compiler/lexer"
+	"SPL-compiler/token"
+	"strings"
+)
+
+// Completion is a candidate for the word at the cursor. Kind is
+// "variable", "procedure", "function" or "keyword"; Detail says what kind
+// of variable it is.
+type Completion struct {
+	Label  string
+	Kind   string
+	Detail string
+}
+
+// Complete returns what may be written at offset in source: the
+// variables visible there, procedures where an instruction starts,
+// functions on the right of an assignment and the keywords the grammar
+// allows next. Only the beginning of the word at the cursor has to match.
+//
+// Complete works on the tokens before the cursor rather than on the
+// syntax tree, so it works in programs with syntax errors. It replays the
+// declarations on an AuxillaryStack the way scoping does, except that the
+// globals stay bound inside the definitions.
+func Complete(source string, offset int) []Completion {
+	tokens := lexer.TokenizeInput(source)
+	c := &completer{stack: Empty(), procs: definedNames(tokens, token.PROC), funcs: definedNames(tokens, token.FUNC)}
+	for _, tok := range tokens {
+		if tok.Type == token.EOF || tok.Offset >= offset {
+			break
+		}
+		if tok.EndOffset >= offset && isWord(tok) {
+			c.prefix = tok.Literal[:offset-tok.Offset]
+			break
+		}
+		if tok.Type != token.ILLEGAL {
+			c.feed(tok)
+		}
+	}
+
+	output := make([]Completion, 0)
+	for _, candidate := range c.candidates() {
+		if strings.HasPrefix(candidate.Label, c.prefix) {
+			output = append(output, candidate)
+		}
+	}
+	return output
+}
+
+// frame is an open brace or parenthesis. kind is one of the section
+// keywords, "def" for the body of a definition, "local" and "var" for
+// declarations, "block" for the body of a loop or branch, "params",
+// "args" and "term".
+type frame struct {
+	kind   string
+	opener token.TokenType // the keyword a block belongs to
+}
+
+type completer struct {
+	stack        *AuxillaryStack
+	kinds        []string // what each binding on the stack declares
+	frames       []frame
+	procs, funcs []string
+	prefix       string
+
+	prev     lexer.Token // the last token before the cursor
+	closed   frame       // the frame the last closing brace ended
+	sections int         // the sections closed so far
+	inFunc   bool        // the definition being read is a function
+	keyword  token.TokenType
+}
+
+func (c *completer) top() frame {
+	if len(c.frames) == 0 {
+		return frame{}
+	}
+	return c.frames[len(c.frames)-1]
+}
+
+func (c *completer) push(kind string) {
+	c.frames = append(c.frames, frame{kind: kind, opener: c.keyword})
+}
+
+func (c *completer) pop() frame {
+	top := c.top()
+	if len(c.frames) > 0 {
+		c.frames = c.frames[:len(c.frames)-1]
+	}
+	switch top.kind {
+	case "def", "main":
+		c.stack.exit()
+	case "glob", "proc", "func":
+		c.sections++
+	}
+	if top.kind == "main" {
+		c.sections++
+	}
+	return top
+}
+
+func (c *completer) bind(name, kind string) {
+	c.stack.bind(name, len(c.kinds))
+	c.kinds = append(c.kinds, kind)
+}
+
+// feed updates the scopes and open frames with the next token.
+func (c *completer) feed(tok lexer.Token) {
+	defer func() { c.prev = tok }()
+
+	switch tok.Type {
+	case token.IDENT:
+		switch c.top().kind {
+		case "glob":
+			c.bind(tok.Literal, "global variable")
+		case "params":
+			c.bind(tok.Literal, "parameter")
+		case "local":
+			c.bind(tok.Literal, "local variable")
+		case "var":
+			c.bind(tok.Literal, "variable")
+		}
+	case token.LPAREN:
+		switch top := c.top().kind; {
+		case (top == "proc" || top == "func") && c.prev.Type == token.IDENT:
+			c.stack.enter(len(c.kinds))
+			c.inFunc = top == "func"
+			c.push("params")
+		case c.prev.Type == token.IDENT && top != "term":
+			c.push("args")
+		default:
+			c.push("term")
+		}
+	case token.RPAREN:
+		if kind := c.top().kind; kind == "params" || kind == "args" || kind == "term" {
+			c.pop()
+		}
+	case token.LBRACE:
+		switch top := c.top().kind; {
+		case c.prev.Type == token.GLOB:
+			c.push("glob")
+		case c.prev.Type == token.PROC:
+			c.push("proc")
+		case c.prev.Type == token.FUNC:
+			c.push("func")
+		case c.prev.Type == token.MAIN:
+			c.stack.enter(len(c.kinds))
+			c.push("main")
+		case c.prev.Type == token.LOCAL:
+			c.push("local")
+		case c.prev.Type == token.VAR:
+			c.push("var")
+		case top == "proc" || top == "func":
+			c.push("def")
+		default:
+			c.push("block")
+		}
+	case token.RBRACE:
+		// Parentheses left open by a syntax error end with the brace.
+		for kind := c.top().kind; kind == "params" || kind == "args" || kind == "term"; kind = c.top().kind {
+			c.pop()
+		}
+		c.closed = c.pop()
+	case token.WHILE, token.IF, token.ELSE, token.DO:
+		c.keyword = tok.Type
+	}
+}
+
+// candidates lists everything that fits the grammar state at the cursor.
+func (c *completer) candidates() []Completion {
+	top := c.top()
+	switch top.kind {
+	case "":
+		sections := []string{"glob", "proc", "func", "main"}
+		if c.sections < len(sections) {
+			return keywords(sections[c.sections])
+		}
+		return nil
+	case "glob", "proc", "func", "local", "var", "params":
+		return nil
+	case "args":
+		return c.variables()
+	case "term":
+		switch c.prev.Type {
+		case token.LPAREN:
+			return append(c.variables(), keywords("neg", "not")...)
+		case token.IDENT, token.INT, token.RPAREN:
+			return keywords("eq", ">", "or", "and", "plus", "minus", "mult", "div")
+		}
+		return c.variables()
+	}
+
+	// The body of a definition, main or a block.
+	switch {
+	case c.prev.Type == token.LBRACE && top.kind == "def":
+		return keywords("local")
+	case c.prev.Type == token.LBRACE && top.kind == "main":
+		return keywords("var")
+	case c.prev.Type == token.LBRACE, c.prev.Type == token.SEMICOLON,
+		c.prev.Type == token.RBRACE && (c.closed.kind == "local" || c.closed.kind == "var"):
+		statements := append(c.variables(), c.calls(c.procs, "procedure")...)
+		statements = append(statements, keywords("halt", "print", "while", "do", "if")...)
+		if top.kind == "def" && c.inFunc && c.prev.Type == token.SEMICOLON {
+			statements = append(statements, keywords("return")...)
+		}
+		return statements
+	case c.prev.Type == token.RBRACE && c.closed.kind == "block":
+		switch c.closed.opener {
+		case token.IF:
+			return keywords("else")
+		case token.DO:
+			return keywords("until")
+		}
+	case c.prev.Type == token.ASSIGN:
+		return append(c.variables(), c.calls(c.funcs, "function")...)
+	case c.prev.Type == token.PRINT, c.prev.Type == token.WHILE, c.prev.Type == token.IF,
+		c.prev.Type == token.UNTIL, c.prev.Type == token.RETURN:
+		return c.variables()
+	}
+	return nil
+}
+
+// variables lists the variables bound on the stack, inner scopes first,
+// without the ones they shadow.
+func (c *completer) variables() []Completion {
+	output := make([]Completion, 0)
+	seen := make(map[string]bool)
+	for i := len(*c.stack) - 1; i >= 0; i-- {
+		binding := (*c.stack)[i]
+		if binding.name == "$" || seen[binding.name] {
+			continue
+		}
+		seen[binding.name] = true
+		output = append(output, Completion{binding.name, "variable", c.kinds[binding.nodeID]})
+	}
+	return output
+}
+
+func (c *completer) calls(names []string, kind string) []Completion {
+	output := make([]Completion, len(names))
+	for i, name := range names {
+		output[i] = Completion{name, kind, ""}
+	}
+	return output
+}
+
+func keywords(words ...string) []Completion {
+	output := make([]Completion, len(words))
+	for i, word := range words {
+		output[i] = Completion{word, "keyword", ""}
+	}
+	return output
+}
+
+// definedNames returns the names defined in the proc or func section of a
+// token stream: the identifiers directly inside the section that are
+// followed by a parenthesis.
+func definedNames(tokens []lexer.Token, section token.TokenType) []string {
+	names := make([]string, 0)
+	depth := -1
+	for i, tok := range tokens {
+		switch {
+		case tok.Type == section && depth < 0:
+			depth = 0
+		case depth < 0:
+		case tok.Type == token.LBRACE:
+			depth++
+		case tok.Type == token.RBRACE:
+			depth--
+			if depth == 0 {
+				return names
+			}
+		case depth == 1 && tok.Type == token.IDENT && i+1 < len(tokens) && tokens[i+1].Type == token.LPAREN:
+			names = append(names, tok.Literal)
+		case depth <= 0 && tok.Type != token.LBRACE:
+			return names
+		}
+	}
+	return names
+}
+
+// isWord reports whether a token is a name or keyword the cursor can be
+// in the middle of.
+func isWord(tok lexer.Token) bool {
+	if tok.Type == token.IDENT {
+		return true
+	}
+	for i := 0; i < len(tok.Literal); i++ {
+		if tok.Literal[i] < 'a' || tok.Literal[i] > 'z' {
+			return false
+		}
+	}
+	return tok.Type != token.STRING && tok.Literal != ""
+}
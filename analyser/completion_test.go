@@ -0,0 +1,59 @@
+package analyser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	// Each | marks a cursor position; the source is the text without it.
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Testing the first section", "|", []string{"glob"}},
+		{"Testing the next section", "glob { } pr|", []string{"proc"}},
+		{"Testing a declaration list", "glob { |", nil},
+		{"Testing the start of a definition", "glob { g } proc { p(a) { |", []string{"local"}},
+		{"Testing the start of main", "glob { g } proc { } func { } main { |", []string{"var"}},
+		{"Testing a statement in a procedure", "glob { g } proc { p(a) { local { b } | } q() { local { } halt } } func { f(n) { local { } halt; return n } } main { var { x } halt }",
+			[]string{"b", "a", "g", "p", "q", "halt", "print", "while", "do", "if"}},
+		{"Testing a right-hand side", "glob { g } proc { p(a) { local { } halt } } func { f(n) { local { } halt; return n } } main { var { x } x = |",
+			[]string{"x", "g", "f"}},
+		{"Testing a prefix", "glob { g } proc { } func { } main { var { x whole } w|", []string{"whole", "while"}},
+		{"Testing a function body", "glob { } proc { } func { f(n) { local { } n = 1; |", []string{"n", "halt", "print", "while", "do", "if", "return"}},
+		{"Testing an operator", "glob { } proc { } func { } main { var { x } x = (x |", []string{"eq", ">", "or", "and", "plus", "minus", "mult", "div"}},
+		{"Testing an opened term", "glob { } proc { } func { } main { var { x } while (|", []string{"x", "neg", "not"}},
+		{"Testing arguments", "glob { g } proc { p(a) { local { } halt } } func { } main { var { x } p(|", []string{"x", "g"}},
+		{"Testing else", "glob { } proc { } func { } main { var { x } if (x > 1) { halt } |", []string{"else"}},
+		{"Testing until", "glob { } proc { } func { } main { var { x } do { halt } u|", []string{"until"}},
+		{"Testing a scope that ended", "glob { } proc { p(a) { local { b } halt } } func { } main { var { x } |", []string{"x", "p", "halt", "print", "while", "do", "if"}},
+		{"Testing a syntax error before the cursor", "glob { } proc { } func { } main { var { x } x = = ; |", []string{"x", "halt", "print", "while", "do", "if"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset := strings.Index(tt.input, "|")
+			labels := make([]string, 0)
+			for _, completion := range Complete(strings.Replace(tt.input, "|", "", 1), offset) {
+				labels = append(labels, completion.Label)
+			}
+			if len(tt.expected) == 0 && len(labels) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(labels, tt.expected) {
+				t.Errorf("got %v, expected %v", labels, tt.expected)
+			}
+		})
+	}
+
+	completions := Complete("glob { g } proc { p(a) { local { b } ", 37)
+	kinds := map[string]string{}
+	for _, completion := range completions {
+		kinds[completion.Label] = completion.Kind + " " + completion.Detail
+	}
+	if kinds["a"] != "variable parameter" || kinds["g"] != "variable global variable" || kinds["p"] != "procedure " {
+		t.Errorf("got %v", kinds)
+	}
+}
@@ -0,0 +1,154 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+	"strings"
+)
+
+// ValidateDot renders one of the graphs of a program in Graphviz DOT: "ast"
+// only needs a parsed program, "callgraph" and "cfg" an analysed one.
+func ValidateDot(root *parser.ASTNode, graph string) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	switch graph {
+	case "ast":
+		return DotAST(root), nil
+	case "callgraph":
+		return DotCallGraph(root), nil
+	case "cfg":
+		return DotCFG(root), nil
+	default:
+		return "", fmt.Errorf("unknown graph %q, expected ast, callgraph or cfg", graph)
+	}
+}
+
+// DotAST draws every node with its type, name and ID.
+func DotAST(root *parser.ASTNode) string {
+	output := []string{
+		"digraph AST {",
+		`	node [shape=box, fontname="monospace"];`,
+	}
+	var walk func(node *parser.ASTNode)
+	walk = func(node *parser.ASTNode) {
+		label := node.Type
+		if node.Name != "" {
+			label += ": " + node.Name
+		}
+		label += fmt.Sprintf("\n[%d]", node.ID)
+		output = append(output, fmt.Sprintf("	n%d [label=%s];", node.ID, dotQuote(label)))
+		for _, child := range node.Children {
+			output = append(output, fmt.Sprintf("	n%d -> n%d;", node.ID, child.ID))
+			walk(child)
+		}
+	}
+	walk(root)
+	return strings.Join(append(output, "}", ""), "\n")
+}
+
+// DotCallGraph draws an edge from each caller to each procedure or
+// function it calls, labelled with the number of call sites when there is
+// more than one.
+func DotCallGraph(root *parser.ASTNode) string {
+	graph := BuildCallGraph(root)
+	output := []string{
+		"digraph CallGraph {",
+		`	node [fontname="monospace"];`,
+	}
+	for _, caller := range graph.Callers {
+		switch caller.Type {
+		case PDEF:
+			output = append(output, fmt.Sprintf("	n%d [label=%s, shape=box];", caller.ID, dotQuote("proc "+caller.Children[0].Name)))
+		case FDEF:
+			output = append(output, fmt.Sprintf("	n%d [label=%s, shape=ellipse];", caller.ID, dotQuote("func "+caller.Children[0].Name)))
+		default:
+			output = append(output, fmt.Sprintf("	n%d [label=\"main\", shape=doublecircle];", caller.ID))
+		}
+	}
+	for _, caller := range graph.Callers {
+		counts := make(map[*parser.ASTNode]int)
+		order := make([]*parser.ASTNode, 0)
+		for _, callee := range graph.Calls[caller] {
+			if counts[callee] == 0 {
+				order = append(order, callee)
+			}
+			counts[callee]++
+		}
+		for _, callee := range order {
+			edge := fmt.Sprintf("	n%d -> n%d", caller.ID, callee.ID)
+			if counts[callee] > 1 {
+				edge += fmt.Sprintf(" [label=\"%d\"]", counts[callee])
+			}
+			output = append(output, edge+";")
+		}
+	}
+	return strings.Join(append(output, "}", ""), "\n")
+}
+
+// DotCFG draws the control-flow graph of the intermediate code of every
+// procedure and function body and of the main program, each in a cluster
+// of its own.
+func DotCFG(root *parser.ASTNode) string {
+	output := []string{
+		"digraph CFG {",
+		`	node [shape=box, fontname="monospace"];`,
+	}
+	cluster := func(name, title string, program []string) {
+		output = append(output,
+			fmt.Sprintf("	subgraph cluster_%s {", name),
+			fmt.Sprintf("		label=%s;", dotQuote(title)),
+			fmt.Sprintf("		%s_entry [label=\"entry\", shape=oval];", name),
+			fmt.Sprintf("		%s_exit [label=\"exit\", shape=oval];", name))
+		blocks := ControlFlowGraph(program)
+		node := func(index int) string {
+			if index == ExitBlock {
+				return name + "_exit"
+			}
+			return fmt.Sprintf("%s_b%d", name, index)
+		}
+		for i, block := range blocks {
+			lines := make([]string, len(block.Lines))
+			for j, line := range block.Lines {
+				lines[j] = dotEscape(line)
+			}
+			output = append(output, fmt.Sprintf("		%s [label=\"%s\\l\"];", node(i), strings.Join(lines, "\\l")))
+		}
+		if len(blocks) == 0 {
+			output = append(output, fmt.Sprintf("		%s_entry -> %s_exit;", name, name))
+		} else {
+			output = append(output, fmt.Sprintf("		%s_entry -> %s;", name, node(0)))
+		}
+		for i, block := range blocks {
+			for j, successor := range block.Successors {
+				edge := fmt.Sprintf("		%s -> %s", node(i), node(successor))
+				if len(block.Successors) == 2 {
+					edge += []string{" [label=\"true\"]", " [label=\"false\"]"}[j]
+				}
+				output = append(output, edge+";")
+			}
+		}
+		output = append(output, "	}")
+	}
+
+	for _, def := range definitions(root) {
+		kind := "proc"
+		if def.Type == FDEF {
+			kind = "func"
+		}
+		cluster(fmt.Sprintf("def%d", def.ID), kind+" "+def.Children[0].Name, GenerateDefinition(def))
+	}
+	cluster("main", "main", GenerateProgram(root))
+	return strings.Join(append(output, "}", ""), "\n")
+}
+
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
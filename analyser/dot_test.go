@@ -0,0 +1,107 @@
+package analyser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestControlFlowGraph(t *testing.T) {
+	tests := []struct {
+		name       string
+		program    []string
+		starts     []int
+		successors [][]int
+	}{
+		{"Testing straight-line code", []string{"x = 1", "PRINT x"}, []int{0}, [][]int{{ExitBlock}}},
+		{"Testing a conditional jump", []string{"IF x > 0 THEN l0", "PRINT x", "REM l0", "STOP"}, []int{0, 1, 2}, [][]int{{2, 1}, {2}, {ExitBlock}}},
+		{"Testing a loop", []string{"REM l0", "x = x - 1", "IF x > 0 THEN l0", "PRINT x"}, []int{0, 3}, [][]int{{0, 1}, {ExitBlock}}},
+		{"Testing code after STOP", []string{"STOP", "GOTO l1", "REM l1"}, []int{0, 1, 2}, [][]int{{ExitBlock}, {2}, {ExitBlock}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := ControlFlowGraph(tt.program)
+			starts := make([]int, len(blocks))
+			successors := make([][]int, len(blocks))
+			for i, block := range blocks {
+				starts[i] = block.Start
+				successors[i] = block.Successors
+			}
+			if !reflect.DeepEqual(starts, tt.starts) || !reflect.DeepEqual(successors, tt.successors) {
+				t.Errorf("got blocks at %v with successors %v, expected %v and %v", starts, successors, tt.starts, tt.successors)
+			}
+		})
+	}
+}
+
+var dotProgram = `glob { }
+proc {
+	p(a) { local { } print a }
+	q() { local { } while (1 > 0) { if (1 > 0) { p(1) } else { p(2) } } }
+}
+func {
+	f(n) { local { } p(n); return n }
+}
+main {
+	var { x }
+	q();
+	x = f(1);
+	print x
+}`
+
+func TestBuildCallGraph(t *testing.T) {
+	root := analyseForTest(t, dotProgram)
+	graph := BuildCallGraph(root)
+	names := make(map[string][]string)
+	for _, caller := range graph.Callers {
+		name := "main"
+		if caller.Type != MAINPROG {
+			name = caller.Children[0].Name
+		}
+		names[name] = make([]string, 0)
+		for _, callee := range graph.Calls[caller] {
+			names[name] = append(names[name], callee.Children[0].Name)
+		}
+	}
+	expected := map[string][]string{
+		"p":    {},
+		"q":    {"p", "p"},
+		"f":    {"p"},
+		"main": {"q", "f"},
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("got call graph %v, expected %v", names, expected)
+	}
+}
+
+func TestDot(t *testing.T) {
+	root := analyseForTest(t, dotProgram)
+	tests := []struct {
+		graph    string
+		expected []string
+	}{
+		{"ast", []string{"digraph AST {", `label="NAME: p\n[`, `label="VAR: x\n[`}},
+		{"callgraph", []string{"digraph CallGraph {", `label="proc q", shape=box`, `label="func f", shape=ellipse`, `label="main", shape=doublecircle`, ` [label="2"];`}},
+		{"cfg", []string{"digraph CFG {", `label="proc p";`, `label="func f";`, "subgraph cluster_main {", `[label="true"];`, "main_entry -> main_b0;", "PRINT "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.graph, func(t *testing.T) {
+			code, err := ValidateDot(root, tt.graph)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(code, expected) {
+					t.Errorf("%s graph does not contain %s", tt.graph, expected)
+				}
+			}
+			if strings.Count(code, "{") != strings.Count(code, "}") {
+				t.Errorf("unbalanced braces in %s graph", tt.graph)
+			}
+		})
+	}
+
+	if _, err := ValidateDot(root, "dominators"); err == nil {
+		t.Errorf("expected an error for an unknown graph")
+	}
+}
@@ -12,6 +12,23 @@ var (
 	letterIndex int
 )
 
+// irLine is a line of intermediate code with the INSTR node it came from.
+// Instructions are generated innermost first, so a line belongs to the
+// innermost instruction enclosing it and is left alone by the outer ones.
+type irLine struct {
+	code   string
+	origin *parser.ASTNode
+}
+
+// ir makes lines that no instruction has claimed yet.
+func ir(code ...string) []irLine {
+	lines := make([]irLine, len(code))
+	for i, c := range code {
+		lines[i] = irLine{code: c}
+	}
+	return lines
+}
+
 func initialiseGenerator() {
 	placeIndex = 0
 	labelIndex = 0
@@ -43,20 +60,62 @@ func keywordSet(candidate string) bool {
 	return slices.Contains(keywords, candidate)
 }
 
-func ValidateCodeGeneration(root *parser.ASTNode) (intrs []string, err error) {
+func ValidateCodeGeneration(root *parser.ASTNode) (intrs []string, origins []*parser.ASTNode, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("%v", r)
 		}
 	}()
 
-	instrs := GenerateProgram(root)
-	return instrs, nil
+	instrs, origins := GenerateIntermediate(root)
+	return instrs, origins, nil
 }
 
 func GenerateProgram(root *parser.ASTNode) []string {
+	instrs, _ := GenerateIntermediate(root)
+	return instrs
+}
+
+// GenerateIntermediate generates the intermediate code of a program along
+// with, for every instruction, the INSTR node it was generated from.
+func GenerateIntermediate(root *parser.ASTNode) ([]string, []*parser.ASTNode) {
+	initialiseGenerator()
+	return split(generateCode(root))
+}
+
+// GenerateDefinition generates the intermediate code of a procedure or
+// function body on its own, as it is inlined at every call site. The
+// result of a function is assigned to a place of its own.
+func GenerateDefinition(def *parser.ASTNode) []string {
 	initialiseGenerator()
-	return generateCode(root)
+	var lines []irLine
+	if def.Type == FDEF {
+		lines = inlineFunc(def, getUniquePlace())
+	} else {
+		lines = inlineProc(def)
+	}
+	instrs, _ := split(lines)
+	return instrs
+}
+
+// split separates generated lines into their code and their origins.
+func split(lines []irLine) ([]string, []*parser.ASTNode) {
+	instrs := make([]string, len(lines))
+	origins := make([]*parser.ASTNode, len(lines))
+	for i, line := range lines {
+		instrs[i], origins[i] = line.code, line.origin
+	}
+	return instrs, origins
+}
+
+// markOrigin gives node as the origin of the lines that have none yet.
+func markOrigin(lines []irLine, node *parser.ASTNode) []irLine {
+	for i := range lines {
+		if lines[i].origin == nil {
+			lines[i].origin = node
+		}
+	}
+	return lines
 }
 
 func newLabel() string {
@@ -65,9 +124,9 @@ func newLabel() string {
 	return newIndex
 }
 
-func generateCode(node *parser.ASTNode) []string {
+func generateCode(node *parser.ASTNode) []irLine {
 	if node == nil {
-		return []string{}
+		return []irLine{}
 	}
 
 	switch node.Type {
@@ -78,7 +137,7 @@ func generateCode(node *parser.ASTNode) []string {
 	case ALGO:
 		return generateAlgo(node)
 	case INSTR:
-		return generateInstr(node)
+		return markOrigin(generateInstr(node), node)
 	case ASSIGN:
 		return generateAssign(node)
 	case LOOP:
@@ -90,37 +149,37 @@ func generateCode(node *parser.ASTNode) []string {
 	}
 }
 
-func generateProgram(node *parser.ASTNode) []string {
+func generateProgram(node *parser.ASTNode) []irLine {
 	return generateMainProg(node.Children[3])
 }
 
-func generateMainProg(node *parser.ASTNode) []string {
+func generateMainProg(node *parser.ASTNode) []irLine {
 	return generateAlgo(node.Children[1])
 }
 
-func generateAlgo(node *parser.ASTNode) []string {
-	output := make([]string, 0)
+func generateAlgo(node *parser.ASTNode) []irLine {
+	output := make([]irLine, 0)
 	for _, child := range node.Children {
 		output = append(output, generateCode(child)...)
 	}
 	return output
 }
 
-func generateInstr(node *parser.ASTNode) []string {
+func generateInstr(node *parser.ASTNode) []irLine {
 	switch node.Name {
 	case "halt":
-		return []string{"STOP"}
+		return ir("STOP")
 	case "print":
-		return []string{fmt.Sprintf("PRINT %s", getOutput(node.Children[0]))}
+		return ir(fmt.Sprintf("PRINT %s", getOutput(node.Children[0])))
 	case "call":
 		code, argPlaces := generateInput(node.Children[1])
 		procNodeID := symbolTable[int(node.Children[0].ID)].declarationNode
 		procNode := parser.GetDefNodeByNameID(rootNode, procNodeID)
 		inlineCode := inlineProc(procNode)
-		output := make([]string, 0)
+		output := make([]irLine, 0)
 		output = append(output, code...)
 		for i, param := range procNode.Children[1].Children[0].Children {
-			output = append(output, fmt.Sprintf("%s = %s", getVar(param), argPlaces[i]))
+			output = append(output, ir(fmt.Sprintf("%s = %s", getVar(param), argPlaces[i]))...)
 		}
 		output = append(output, inlineCode...)
 		return output
@@ -137,31 +196,31 @@ func getOutput(node *parser.ASTNode) string {
 	}
 }
 
-func generateInput(node *parser.ASTNode) ([]string, []string) {
-	assignments := make([]string, 0)
+func generateInput(node *parser.ASTNode) ([]irLine, []string) {
+	assignments := make([]irLine, 0)
 	places := make([]string, 0)
 	for _, child := range node.Children {
 		place := getUniquePlace()
 		value := getAtom(child)
-		assignments = append(assignments, fmt.Sprintf("%s = %s", place, value))
+		assignments = append(assignments, ir(fmt.Sprintf("%s = %s", place, value))...)
 		places = append(places, place)
 	}
 	return assignments, places
 }
 
-func inlineProc(node *parser.ASTNode) []string {
+func inlineProc(node *parser.ASTNode) []irLine {
 	if node.Type != "PDEF" {
 		panic(fmt.Sprintf("expected 'pdef' Proc node name but got %s", node.Type))
 	}
 	return generateAlgo(node.Children[2].Children[1])
 }
 
-func inlineFunc(node *parser.ASTNode, place string) []string {
+func inlineFunc(node *parser.ASTNode, place string) []irLine {
 	if node.Type != "FDEF" {
 		panic(fmt.Sprintf("expected 'fdef' Func node name but got %s", node.Type))
 	}
 	algo := generateAlgo(node.Children[2].Children[1])
-	return append(algo, fmt.Sprintf("%s = %s", place, getAtom(node.Children[3])))
+	return append(algo, ir(fmt.Sprintf("%s = %s", place, getAtom(node.Children[3])))...)
 }
 
 func getAtom(node *parser.ASTNode) string {
@@ -176,7 +235,7 @@ func getVar(node *parser.ASTNode) string {
 	return symbolTable[int(node.ID)].uniqueID
 }
 
-func generateAssign(node *parser.ASTNode) []string {
+func generateAssign(node *parser.ASTNode) []irLine {
 	if node.Name == "call" {
 		place := getUniquePlace()
 		vname := symbolTable[int(node.Children[0].ID)].uniqueID
@@ -184,27 +243,27 @@ func generateAssign(node *parser.ASTNode) []string {
 		funcNodeID := symbolTable[int(node.Children[1].ID)].declarationNode
 		funcNode := parser.GetDefNodeByNameID(rootNode, funcNodeID)
 		inlineCode := inlineFunc(funcNode, place)
-		output := make([]string, 0)
+		output := make([]irLine, 0)
 		output = append(output, code...)
 		for i, param := range funcNode.Children[1].Children[0].Children {
-			output = append(output, fmt.Sprintf("%s = %s", getVar(param), argPlaces[i]))
+			output = append(output, ir(fmt.Sprintf("%s = %s", getVar(param), argPlaces[i]))...)
 		}
 		output = append(output, inlineCode...)
 
 		return append(
 			output,
-			fmt.Sprintf("%s = %s", vname, place),
+			ir(fmt.Sprintf("%s = %s", vname, place))...,
 		)
 
 	} else {
 		place := getUniquePlace()
 		vname := symbolTable[int(node.Children[0].ID)].uniqueID
 		code := generateTerm(node.Children[1], place)
-		return append(code, fmt.Sprintf("%s = %s", vname, place))
+		return append(code, ir(fmt.Sprintf("%s = %s", vname, place))...)
 	}
 }
 
-func generateLoop(node *parser.ASTNode) []string {
+func generateLoop(node *parser.ASTNode) []irLine {
 	switch node.Name {
 	case "while":
 		labelCond := newLabel()
@@ -212,12 +271,10 @@ func generateLoop(node *parser.ASTNode) []string {
 		labelExit := newLabel()
 		cond := generateCond(node.Children[0], labelStart, labelExit)
 		algo := generateAlgo(node.Children[1])
-		part0 := append([]string{
-			fmt.Sprintf("REM %s", labelCond),
-		}, cond...)
+		part0 := append(ir(fmt.Sprintf("REM %s", labelCond)), cond...)
 		part1 := append(
 			part0,
-			fmt.Sprintf("REM %s", labelStart),
+			ir(fmt.Sprintf("REM %s", labelStart))...,
 		)
 		part2 := append(
 			part1,
@@ -225,24 +282,22 @@ func generateLoop(node *parser.ASTNode) []string {
 		)
 		return append(
 			part2,
-			fmt.Sprintf("GOTO %s", labelCond),
-			fmt.Sprintf("REM %s", labelExit))
+			ir(fmt.Sprintf("GOTO %s", labelCond),
+				fmt.Sprintf("REM %s", labelExit))...)
 
 	case "do":
 		labelStart := newLabel()
 		labelExit := newLabel()
 		algo := generateAlgo(node.Children[0])
 		cond := generateCond(node.Children[1], labelExit, labelStart)
-		part0 := append([]string{
-			fmt.Sprintf("REM %s", labelStart),
-		}, algo...)
+		part0 := append(ir(fmt.Sprintf("REM %s", labelStart)), algo...)
 		part1 := append(
 			part0,
 			cond...,
 		)
 		return append(
 			part1,
-			fmt.Sprintf("REM %s", labelExit),
+			ir(fmt.Sprintf("REM %s", labelExit))...,
 		)
 
 	default:
@@ -250,7 +305,7 @@ func generateLoop(node *parser.ASTNode) []string {
 	}
 }
 
-func generateBranch(node *parser.ASTNode) []string {
+func generateBranch(node *parser.ASTNode) []irLine {
 	switch node.Name {
 	case "if":
 		labelStart := newLabel()
@@ -258,7 +313,7 @@ func generateBranch(node *parser.ASTNode) []string {
 		cond := generateCond(node.Children[0], labelStart, labelExit)
 		part1 := append(
 			cond,
-			fmt.Sprintf("REM %s", labelStart),
+			ir(fmt.Sprintf("REM %s", labelStart))...,
 		)
 		part2 := append(
 			part1,
@@ -266,7 +321,7 @@ func generateBranch(node *parser.ASTNode) []string {
 		)
 		return append(
 			part2,
-			fmt.Sprintf("REM %s", labelExit),
+			ir(fmt.Sprintf("REM %s", labelExit))...,
 		)
 	case "ifelse":
 		labelStart := newLabel()
@@ -276,7 +331,7 @@ func generateBranch(node *parser.ASTNode) []string {
 		cond := generateCondElse(node.Children[0], labelStart, labelExit, elseAlgo)
 		part1 := append(
 			cond,
-			fmt.Sprintf("REM %s", labelStart),
+			ir(fmt.Sprintf("REM %s", labelStart))...,
 		)
 		part2 := append(
 			part1,
@@ -284,7 +339,7 @@ func generateBranch(node *parser.ASTNode) []string {
 		)
 		part3 := append(
 			part2,
-			fmt.Sprintf("REM %s", labelExit),
+			ir(fmt.Sprintf("REM %s", labelExit))...,
 		)
 		return part3
 	default:
@@ -292,7 +347,7 @@ func generateBranch(node *parser.ASTNode) []string {
 	}
 }
 
-func generateCond(node *parser.ASTNode, labelT, labelF string) []string {
+func generateCond(node *parser.ASTNode, labelT, labelF string) []irLine {
 	switch node.Name {
 	case "unop":
 		if node.Children[0].Name != "not" {
@@ -306,13 +361,13 @@ func generateCond(node *parser.ASTNode, labelT, labelF string) []string {
 			arg2 := newLabel()
 			codeL := generateCond(node.Children[0], arg2, labelF)
 			codeR := generateCond(node.Children[2], labelT, labelF)
-			part0 := append(codeL, fmt.Sprintf("REM %s", arg2))
+			part0 := append(codeL, ir(fmt.Sprintf("REM %s", arg2))...)
 			return append(part0, codeR...)
 		case "or":
 			arg2 := newLabel()
 			codeL := generateCond(node.Children[0], labelT, arg2)
 			codeR := generateCond(node.Children[2], labelT, labelF)
-			part0 := append(codeL, fmt.Sprintf("REM %s", arg2))
+			part0 := append(codeL, ir(fmt.Sprintf("REM %s", arg2))...)
 			return append(part0, codeR...)
 		}
 		t1 := getUniquePlace()
@@ -323,8 +378,8 @@ func generateCond(node *parser.ASTNode, labelT, labelF string) []string {
 		part0 := append(codeL, codeR...)
 		part1 := append(
 			part0,
-			fmt.Sprintf("IF %s %s %s THEN %s", t1, binop, t2, labelT),
-			fmt.Sprintf("GOTO %s", labelF),
+			ir(fmt.Sprintf("IF %s %s %s THEN %s", t1, binop, t2, labelT),
+				fmt.Sprintf("GOTO %s", labelF))...,
 		)
 		return part1
 	default:
@@ -332,7 +387,7 @@ func generateCond(node *parser.ASTNode, labelT, labelF string) []string {
 	}
 }
 
-func generateCondElse(node *parser.ASTNode, labelT, labelF string, elseInstrs []string) []string {
+func generateCondElse(node *parser.ASTNode, labelT, labelF string, elseInstrs []irLine) []irLine {
 	switch node.Name {
 	case "unop":
 		if node.Children[0].Name != "not" {
@@ -346,13 +401,13 @@ func generateCondElse(node *parser.ASTNode, labelT, labelF string, elseInstrs []
 			arg2 := newLabel()
 			codeL := generateCondElse(node.Children[0], arg2, labelF, elseInstrs)
 			codeR := generateCond(node.Children[2], labelT, labelF)
-			part0 := append(codeL, fmt.Sprintf("REM %s", arg2))
+			part0 := append(codeL, ir(fmt.Sprintf("REM %s", arg2))...)
 			return append(part0, codeR...)
 		case "or":
 			arg2 := newLabel()
 			codeL := generateCond(node.Children[0], labelT, arg2)
 			codeR := generateCondElse(node.Children[2], labelT, labelF, elseInstrs)
-			part0 := append(codeL, fmt.Sprintf("REM %s", arg2))
+			part0 := append(codeL, ir(fmt.Sprintf("REM %s", arg2))...)
 			return append(part0, codeR...)
 		}
 		t1 := getUniquePlace()
@@ -363,7 +418,7 @@ func generateCondElse(node *parser.ASTNode, labelT, labelF string, elseInstrs []
 		part0 := append(codeL, codeR...)
 		part1 := append(
 			part0,
-			fmt.Sprintf("IF %s %s %s THEN %s", t1, binop, t2, labelT),
+			ir(fmt.Sprintf("IF %s %s %s THEN %s", t1, binop, t2, labelT))...,
 		)
 		part2 := append(
 			part1,
@@ -371,7 +426,7 @@ func generateCondElse(node *parser.ASTNode, labelT, labelF string, elseInstrs []
 		)
 		part3 := append(
 			part2,
-			fmt.Sprintf("GOTO %s", labelF),
+			ir(fmt.Sprintf("GOTO %s", labelF))...,
 		)
 		return part3
 	default:
@@ -379,11 +434,11 @@ func generateCondElse(node *parser.ASTNode, labelT, labelF string, elseInstrs []
 	}
 }
 
-func generateTerm(node *parser.ASTNode, place string) []string {
+func generateTerm(node *parser.ASTNode, place string) []irLine {
 	switch node.Name {
 	case "atom":
 		atom := getAtom(node.Children[0])
-		return []string{fmt.Sprintf("%s = %s", place, atom)}
+		return ir(fmt.Sprintf("%s = %s", place, atom))
 	case "unop":
 		if node.Children[0].Name != "neg" {
 			panic("expected 'neg' UnOp in Term node")
@@ -391,7 +446,7 @@ func generateTerm(node *parser.ASTNode, place string) []string {
 		t0 := getUniquePlace()
 		unop := getUnOp(node.Children[0])
 		code := generateTerm(node.Children[1], t0)
-		return append(code, fmt.Sprintf("%s = %s%s", place, unop, t0))
+		return append(code, ir(fmt.Sprintf("%s = %s%s", place, unop, t0))...)
 	case "binop":
 		if node.Children[1].Name == "and" || node.Children[1].Name == "or" {
 			panic("expected non-boolean BinOp in Term node")
@@ -402,7 +457,7 @@ func generateTerm(node *parser.ASTNode, place string) []string {
 		binop := getBinOp(node.Children[1])
 		codeR := generateTerm(node.Children[2], t1)
 		part0 := append(codeL, codeR...)
-		return append(part0, fmt.Sprintf("%s = %s %s %s", place, t0, binop, t1))
+		return append(part0, ir(fmt.Sprintf("%s = %s %s %s", place, t0, binop, t1))...)
 	default:
 		panic("expected 'atom', 'unop', or 'binop' Term node name")
 	}
@@ -0,0 +1,353 @@
+package analyser
+
+import (
+	"SPL-compiler/lexer"
+	"SPL-compiler/parser"
+	"SPL-compiler/token"
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"time"
+)
+
+func ValidateGenerateReport(root *parser.ASTNode, program []string, dialect *BasicDialect, options BasicOptions) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return GenerateReport(root, program, dialect, options), nil
+}
+
+// GenerateReport renders a standalone HTML page showing the SPL source
+// (options.Source), the AST, the symbol table, the intermediate code and
+// its BASIC translation. Every source token, AST node and generated line
+// is tagged with the INSTR node it belongs to, so hovering one highlights
+// the others and clicking scrolls the other panes to them.
+func GenerateReport(root *parser.ASTNode, program []string, dialect *BasicDialect, options BasicOptions) string {
+	instrs := make([]*parser.ASTNode, 0)
+	collectInstructions(root, &instrs)
+
+	nodeOf := func(index int) int64 {
+		if index >= 0 && index < len(options.Origins) && options.Origins[index] != nil {
+			return options.Origins[index].ID
+		}
+		return 0
+	}
+
+	intermediate := make([]reportLine, len(program))
+	for i, line := range program {
+		intermediate[i] = reportLine{Code: line, Node: nodeOf(i), IR: i}
+	}
+
+	basicLines, _, irIndices := translateBasic(program, dialect, options)
+	basic := make([]reportLine, len(basicLines))
+	for i, line := range basicLines {
+		basic[i] = reportLine{Code: line, Node: nodeOf(irIndices[i]), IR: irIndices[i]}
+	}
+
+	var ast strings.Builder
+	reportAST(&ast, root)
+
+	data := struct {
+		Source       []template.HTML
+		AST          template.HTML
+		Symbols      string
+		Intermediate []reportLine
+		Basic        []reportLine
+		Dialect      string
+		GeneratedAt  string
+	}{
+		Source:       reportSource(options.Source, instrs),
+		AST:          template.HTML(ast.String()),
+		Symbols:      FormatSymbolTable(symbolTable),
+		Intermediate: intermediate,
+		Basic:        basic,
+		Dialect:      dialect.Name,
+		GeneratedAt:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	var out bytes.Buffer
+	if err := reportTemplate.Execute(&out, data); err != nil {
+		panic(err)
+	}
+	return out.String()
+}
+
+// reportLine is a generated line with the INSTR node and intermediate
+// instruction it came from. Both are zero or -1 for lines without origin.
+type reportLine struct {
+	Code string
+	Node int64
+	IR   int
+}
+
+func collectInstructions(root *parser.ASTNode, instrs *[]*parser.ASTNode) {
+	parser.Inspect(root, func(node *parser.ASTNode) bool {
+		if node.Type == INSTR {
+			*instrs = append(*instrs, node)
+		}
+		return true
+	})
+}
+
+// innermostInstruction returns the ID of the smallest instruction whose span
+// contains the offset, or zero.
+func innermostInstruction(instrs []*parser.ASTNode, offset int) int64 {
+	var best *parser.ASTNode
+	for _, instr := range instrs {
+		span := instr.Span
+		if span.IsZero() || offset < span.Start.Offset || offset >= span.End.Offset {
+			continue
+		}
+		if best == nil || span.End.Offset-span.Start.Offset < best.Span.End.Offset-best.Span.Start.Offset {
+			best = instr
+		}
+	}
+	if best == nil {
+		return 0
+	}
+	return best.ID
+}
+
+// reportSource highlights the source and splits it into lines. Lexing
+// stops at the first illegal token; the rest is shown as plain text.
+func reportSource(source string, instrs []*parser.ASTNode) []template.HTML {
+	var out strings.Builder
+	position := 0
+	for _, tok := range lexer.TokenizeInput(source) {
+		if tok.Type == token.EOF || tok.Type == token.ILLEGAL || tok.Offset < position {
+			break
+		}
+		out.WriteString(html.EscapeString(source[position:tok.Offset]))
+		fmt.Fprintf(&out, `<span class="tok %s" data-offset="%d"`, tokenClass(tok.Type), tok.Offset)
+		if id := innermostInstruction(instrs, tok.Offset); id != 0 {
+			fmt.Fprintf(&out, ` data-node="%d"`, id)
+		}
+		fmt.Fprintf(&out, ">%s</span>", html.EscapeString(source[tok.Offset:tok.EndOffset]))
+		position = tok.EndOffset
+	}
+	out.WriteString(html.EscapeString(source[position:]))
+
+	lines := make([]template.HTML, 0)
+	for _, line := range strings.Split(out.String(), "\n") {
+		lines = append(lines, template.HTML(line))
+	}
+	return lines
+}
+
+func tokenClass(t token.TokenType) string {
+	switch t {
+	case token.IDENT:
+		return "ident"
+	case token.INT:
+		return "number"
+	case token.STRING:
+		return "string"
+	case token.ASSIGN, token.EQ, token.GT, token.OR, token.AND, token.PLUS,
+		token.MINUS, token.MULT, token.DIV, token.NEG, token.NOT:
+		return "operator"
+	case token.SEMICOLON, token.LPAREN, token.RPAREN, token.LBRACE, token.RBRACE:
+		return "punctuation"
+	default:
+		return "keyword"
+	}
+}
+
+// reportAST writes the tree as nested lists; nodes with children can be
+// collapsed.
+func reportAST(out *strings.Builder, node *parser.ASTNode) {
+	label := fmt.Sprintf(`<span class="node" data-start="%d" data-end="%d"`, node.Span.Start.Offset, node.Span.End.Offset)
+	if node.Type == INSTR {
+		label += fmt.Sprintf(` data-node="%d"`, node.ID)
+	}
+	label += fmt.Sprintf(`>%s`, html.EscapeString(node.Type))
+	if node.Name != "" {
+		label += ": " + html.EscapeString(node.Name)
+	}
+	label += fmt.Sprintf(` <small>[%d]`, node.ID)
+	if !node.Span.IsZero() {
+		label += " " + node.Span.String()
+	}
+	label += "</small></span>"
+
+	if len(node.Children) == 0 {
+		fmt.Fprintf(out, "<li>%s</li>", label)
+		return
+	}
+	fmt.Fprintf(out, "<li><details open><summary>%s</summary><ul>", label)
+	for _, child := range node.Children {
+		reportAST(out, child)
+	}
+	out.WriteString("</ul></details></li>")
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>SPL Compilation Report</title>
+<style>
+  body {
+    font-family: "JetBrains Mono", monospace;
+    background-color: #f9fafb;
+    color: #111827;
+    margin: 40px;
+  }
+  h1 {
+    text-align: center;
+  }
+  .meta {
+    text-align: center;
+    color: #6b7280;
+    font-size: 0.9em;
+    margin-bottom: 20px;
+  }
+  pre, .tree {
+    background-color: #f3f4f6;
+    padding: 16px;
+    border-radius: 8px;
+    overflow: auto;
+    line-height: 1.6;
+    max-height: 36em;
+    counter-reset: line;
+  }
+  .columns {
+    display: flex;
+    gap: 16px;
+  }
+  .columns > section {
+    flex: 1;
+    min-width: 0;
+  }
+  .line {
+    counter-increment: line;
+    white-space: pre;
+  }
+  .line::before {
+    content: counter(line) ": ";
+    color: #9ca3af;
+  }
+  .tree ul {
+    list-style: none;
+    padding-left: 1.2em;
+    margin: 0;
+  }
+  .tree > ul {
+    padding-left: 0;
+  }
+  .tree small {
+    color: #9ca3af;
+  }
+  .keyword { color: #7c3aed; font-weight: bold; }
+  .operator { color: #b45309; }
+  .number { color: #0369a1; }
+  .string { color: #15803d; }
+  .punctuation { color: #6b7280; }
+  [data-node], [data-start] {
+    cursor: pointer;
+  }
+  .highlight {
+    background-color: #fde68a;
+  }
+  .highlight-strong {
+    background-color: #fbbf24;
+  }
+</style>
+</head>
+<body>
+  <h1>SPL Compilation Report</h1>
+  <div class="meta">Generated at {{.GeneratedAt}}</div>
+
+  <div class="columns">
+    <section id="source">
+      <h2>Source</h2>
+      <pre>{{range .Source}}<div class="line">{{.}}</div>{{end}}</pre>
+    </section>
+    <section id="ast">
+      <h2>Abstract Syntax Tree</h2>
+      <div class="tree"><ul>{{.AST}}</ul></div>
+    </section>
+  </div>
+
+  <section id="symbols">
+    <h2>Symbol Table</h2>
+    <pre>{{.Symbols}}</pre>
+  </section>
+
+  <div class="columns">
+    <section id="intermediate">
+      <h2>Intermediate Code</h2>
+      <pre>{{range .Intermediate}}<div class="line"{{if .Node}} data-node="{{.Node}}"{{end}} data-ir="{{.IR}}">{{.Code}}</div>{{end}}</pre>
+    </section>
+    <section id="basic">
+      <h2>BASIC ({{.Dialect}})</h2>
+      <pre>{{range .Basic}}<div class="line"{{if .Node}} data-node="{{.Node}}"{{end}}{{if ge .IR 0}} data-ir="{{.IR}}"{{end}}>{{.Code}}</div>{{end}}</pre>
+    </section>
+  </div>
+
+<script>
+  function clearHighlights() {
+    document.querySelectorAll(".highlight, .highlight-strong").forEach(function (element) {
+      element.classList.remove("highlight", "highlight-strong");
+    });
+  }
+
+  // related returns the elements linked to the given one: everything
+  // belonging to the same instruction, the source tokens an AST node
+  // covers, and the lines of the same intermediate instruction.
+  function related(element) {
+    var found = [];
+    if (element.dataset.node) {
+      found = found.concat(Array.from(document.querySelectorAll('[data-node="' + element.dataset.node + '"]')));
+    }
+    if (element.dataset.start) {
+      var start = Number(element.dataset.start), end = Number(element.dataset.end);
+      document.querySelectorAll("#source [data-offset]").forEach(function (token) {
+        var offset = Number(token.dataset.offset);
+        if (offset >= start && offset < end) {
+          found.push(token);
+        }
+      });
+    }
+    return found;
+  }
+
+  document.addEventListener("mouseover", function (event) {
+    var element = event.target.closest("[data-node], [data-start]");
+    clearHighlights();
+    if (!element) {
+      return;
+    }
+    related(element).forEach(function (other) {
+      other.classList.add("highlight");
+    });
+    if (element.dataset.ir) {
+      document.querySelectorAll('[data-ir="' + element.dataset.ir + '"]').forEach(function (other) {
+        other.classList.add("highlight-strong");
+      });
+    }
+  });
+
+  document.addEventListener("click", function (event) {
+    var element = event.target.closest("[data-node], [data-start]");
+    if (!element) {
+      return;
+    }
+    var section = element.closest("section");
+    var scrolled = {};
+    related(element).forEach(function (other) {
+      var otherSection = other.closest("section");
+      if (otherSection !== section && !scrolled[otherSection.id]) {
+        scrolled[otherSection.id] = true;
+        other.scrollIntoView({ block: "center", behavior: "smooth" });
+      }
+    });
+  });
+</script>
+</body>
+</html>`))
@@ -0,0 +1,50 @@
+package analyser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateReport(t *testing.T) {
+	input := `glob { }
+proc { }
+func { }
+main {
+	var { x }
+	x = 3;
+	print "a<b"
+}`
+	root := analyseForTest(t, input)
+	program, origins := GenerateIntermediate(root)
+	options := BasicOptions{Numbering: DefaultLineNumbering, Origins: origins, Source: input}
+	report, err := ValidateGenerateReport(root, program, BasicDialects["spl"], options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	print := origins[len(program)-1]
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Testing keyword highlighting", `<span class="tok keyword" data-offset="0">glob</span>`},
+		{"Testing escaped strings", fmt.Sprintf(`<span class="tok string" data-offset="%d" data-node="%d">&#34;a&lt;b&#34;</span>`, strings.Index(input, `"a<b"`), print.ID)},
+		{"Testing the AST tree", fmt.Sprintf(`<span class="node" data-start="%d" data-end="%d" data-node="%d">INSTR: print`, print.Span.Start.Offset, print.Span.End.Offset, print.ID)},
+		{"Testing the symbol table", "| NodeID |"},
+		{"Testing linked intermediate code", fmt.Sprintf(`data-node="%d" data-ir="%d">PRINT &#34;a&lt;b&#34;</div>`, print.ID, len(program)-1)},
+		{"Testing linked BASIC code", fmt.Sprintf(`data-node="%d" data-ir="%d">%d  PRINT &#34;a&lt;b&#34;</div>`, print.ID, len(program)-1, len(program)*10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(report, tt.expected) {
+				t.Errorf("report does not contain %s", tt.expected)
+			}
+		})
+	}
+
+	if unlinked := regexp.MustCompile(`<div class="line" data-ir`).FindString(report); unlinked != "" {
+		t.Errorf("found an intermediate line without an instruction")
+	}
+}
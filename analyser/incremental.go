@@ -0,0 +1,316 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"slices"
+	"strings"
+)
+
+// Incremental keeps the analysis of a program that is being edited. An
+// edit inside a single procedure, function or the main program only
+// re-lexes and re-parses that part, splices it into the existing tree
+// and re-runs scoping and type checking for it; the rest of the tree and
+// its symbol table entries are reused. Other edits, and every edit while
+// the program has errors, analyse the whole program again.
+//
+// Update changes the tree and symbol table of the analysis it returned
+// before, so only the latest analysis is valid.
+type Incremental struct {
+	analysis *Analysis
+	names    uniqueNames
+	// Reused reports whether the last Update analysed only one unit.
+	Reused bool
+}
+
+// uniqueNames is the state of getUniqueVar, which other analyses reset.
+type uniqueNames struct {
+	varIndex, curIndex int
+	single             bool
+	recycled           []string
+}
+
+func saveUniqueNames() uniqueNames {
+	return uniqueNames{varIndex, curIndex, single, recycled}
+}
+
+func (n uniqueNames) restore() {
+	varIndex, curIndex, single, recycled = n.varIndex, n.curIndex, n.single, n.recycled
+	charSet = "abcdefghijklmnopqrstuvwxyz"
+}
+
+// NewIncremental analyses source in full.
+func NewIncremental(source string) *Incremental {
+	inc := &Incremental{}
+	inc.full(source)
+	return inc
+}
+
+// Analysis returns the latest analysis.
+func (inc *Incremental) Analysis() *Analysis {
+	return inc.analysis
+}
+
+func (inc *Incremental) full(source string) *Analysis {
+	inc.analysis = Analyse(source)
+	inc.names = saveUniqueNames()
+	inc.Reused = false
+	return inc.analysis
+}
+
+// Update analyses the new text of the program.
+func (inc *Incremental) Update(source string) *Analysis {
+	old := inc.analysis
+	if source == old.Source {
+		return old
+	}
+	if old.Root == nil || len(old.Diagnostics) > 0 {
+		return inc.full(source)
+	}
+
+	// The edit replaced old.Source[start:oldEnd] by source[start:newEnd].
+	start := 0
+	for start < len(source) && start < len(old.Source) && source[start] == old.Source[start] {
+		start++
+	}
+	oldEnd, newEnd := len(old.Source), len(source)
+	for oldEnd > start && newEnd > start && source[newEnd-1] == old.Source[oldEnd-1] {
+		oldEnd--
+		newEnd--
+	}
+
+	unit, section := editedUnit(old.Root, start, oldEnd)
+	if unit == nil {
+		return inc.full(source)
+	}
+	replacement := reparseUnit(source[unit.Span.Start.Offset:unit.Span.End.Offset+newEnd-oldEnd], unit, section)
+	if replacement == nil {
+		return inc.full(source)
+	}
+
+	analysis := &Analysis{Source: source, Root: old.Root, Diagnostics: make([]Diagnostic, 0), symbols: old.symbols}
+	if !analysis.reanalyse(unit, replacement, section, inc.names) {
+		return inc.full(source)
+	}
+	shiftSpans(analysis.Root, unit, old.Source, source, oldEnd, newEnd)
+	parser.Replace(analysis.Root, unit, replacement)
+	inc.analysis = analysis
+	inc.names = saveUniqueNames()
+	inc.Reused = true
+	return analysis
+}
+
+// editedUnit returns the definition or main program that contains the
+// edited range and its section, or nil if there is none. A definition
+// contains the range when it lies before its closing brace, the main
+// program when it lies inside its braces.
+func editedUnit(root *parser.ASTNode, start, end int) (*parser.ASTNode, *parser.ASTNode) {
+	for _, section := range root.Children[1:3] {
+		for _, def := range section.Children {
+			if def.Span.Start.Offset <= start && end < def.Span.End.Offset {
+				return def, section
+			}
+		}
+	}
+	main := root.Children[3]
+	if main.Span.Start.Offset <= start && end < main.Span.End.Offset {
+		return main, main
+	}
+	return nil, nil
+}
+
+// reparseUnit parses the new text of a unit inside an otherwise empty
+// program and moves the result to where the text is in the source. It
+// returns nil unless the text is a single unit of the same kind and, for
+// a definition, with the same name.
+func reparseUnit(text string, unit, section *parser.ASTNode) *parser.ASTNode {
+	var prefix, suffix string
+	switch {
+	case unit == section:
+		prefix, suffix = "glob{}proc{}func{}\n", ""
+	case unit.Type == PDEF:
+		prefix, suffix = "glob{}proc{\n", "\n}func{}main{var{}halt}"
+	default:
+		prefix, suffix = "glob{}proc{}func{\n", "\n}main{var{}halt}"
+	}
+	root, err := parser.ParseSource(prefix + text + suffix)
+	if err != nil {
+		return nil
+	}
+
+	var replacement *parser.ASTNode
+	if unit == section {
+		replacement = root.Children[3]
+	} else {
+		defs := root.Children[1].Children
+		if unit.Type == FDEF {
+			defs = root.Children[2].Children
+		}
+		if len(defs) != 1 || defs[0].Children[0].Name != unit.Children[0].Name {
+			return nil
+		}
+		replacement = defs[0]
+	}
+
+	origin := unit.Span.Start
+	parser.Inspect(replacement, func(node *parser.ASTNode) bool {
+		for _, pos := range []*parser.Position{&node.Span.Start, &node.Span.End} {
+			if pos.Line == 0 {
+				continue
+			}
+			if pos.Line == 2 {
+				pos.Column += origin.Column - 1
+			}
+			pos.Line += origin.Line - 2
+			pos.Offset += origin.Offset - len(prefix)
+		}
+		return true
+	})
+	return replacement
+}
+
+// reanalyse runs scoping and type checking for the replacement of a unit
+// in the state the analysis of the whole program would be in when it
+// reached the unit, then the recursion check for the whole program. On
+// success the symbol table holds the entries of the replacement instead
+// of those of the unit.
+func (a *Analysis) reanalyse(unit, replacement, section *parser.ASTNode, unique uniqueNames) (ok bool) {
+	root := a.Root
+	oldIDs := nodeIDs(unit)
+
+	// What scoping looks up when it reaches the unit: the globals and the
+	// definitions before it.
+	earlier := make([]*parser.ASTNode, 0)
+	for _, def := range definitions(root) {
+		if def == unit {
+			break
+		}
+		earlier = append(earlier, def)
+	}
+	view := make(SymbolTable)
+	for _, node := range root.Children[0].Children {
+		view[int(node.ID)] = a.symbols[int(node.ID)]
+	}
+	for _, def := range earlier {
+		view[int(def.Children[0].ID)] = a.symbols[int(def.Children[0].ID)]
+	}
+
+	// The unit's own unique names are free again unless they are used
+	// outside it; a definition keeps the name it had.
+	names := make(map[string]bool)
+	for id := range oldIDs {
+		if info, ok := a.symbols[id]; ok {
+			names[info.uniqueID] = true
+		}
+	}
+	for id, info := range a.symbols {
+		if !oldIDs[id] {
+			delete(names, info.uniqueID)
+		}
+	}
+	free := make([]string, 0)
+	if unit != section {
+		free = append(free, a.symbols[int(unit.Children[0].ID)].uniqueID)
+		delete(names, free[0])
+	}
+	kept := len(free)
+	for name := range names {
+		free = append(free, name)
+	}
+	slices.Sort(free[kept:])
+
+	symbolTable = view
+	auxStack = Empty()
+	currentScope = auxStack.enter(0)
+	currentScope = auxStack.enter(int(root.ID))
+	GLOBAL_SCOPE = int(root.Children[0].ID)
+	PROCEDURE_SCOPE = int(root.Children[1].ID)
+	FUNCTION_SCOPE = int(root.Children[2].ID)
+	currentScope = int(section.ID)
+	if unit == section {
+		currentScope = int(replacement.ID)
+	}
+	for _, def := range earlier {
+		if slices.Contains(section.Children, def) {
+			auxStack.bind(def.Children[0].Name, int(def.Children[0].ID))
+		}
+	}
+	unique.restore()
+	recycled = append(free, unique.recycled...)
+
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	visitNode(replacement)
+	checkNode(replacement)
+
+	newIDs := nodeIDs(replacement)
+	for id := range oldIDs {
+		delete(a.symbols, id)
+	}
+	for id := range newIDs {
+		if info, ok := view[id]; ok {
+			a.symbols[id] = info
+		}
+	}
+	if unit != section {
+		oldName, newName := int(unit.Children[0].ID), int(replacement.Children[0].ID)
+		for id, info := range a.symbols {
+			if info.declarationNode == oldName {
+				info.declarationNode = newName
+				a.symbols[id] = info
+			}
+		}
+	}
+
+	// The recursion check follows calls into other definitions, so it
+	// runs on the whole program with the replacement in place.
+	symbolTable = a.symbols
+	parser.Replace(root, unit, replacement)
+	defer parser.Replace(root, replacement, unit)
+	CheckRecursion(root)
+	return true
+}
+
+// nodeIDs returns the IDs of the nodes of a subtree.
+func nodeIDs(node *parser.ASTNode) map[int]bool {
+	ids := make(map[int]bool)
+	parser.Inspect(node, func(n *parser.ASTNode) bool {
+		ids[int(n.ID)] = true
+		return true
+	})
+	return ids
+}
+
+// shiftSpans moves the positions after an edit that replaced
+// oldSource[:oldEnd] by newSource[:newEnd], skipping the replaced unit.
+func shiftSpans(root, unit *parser.ASTNode, oldSource, newSource string, oldEnd, newEnd int) {
+	lines := strings.Count(newSource[:newEnd], "\n") - strings.Count(oldSource[:oldEnd], "\n")
+	column := func(source string, end int) int {
+		return end - strings.LastIndexByte(source[:end], '\n')
+	}
+	columns := column(newSource, newEnd) - column(oldSource, oldEnd)
+	oldLine := strings.Count(oldSource[:oldEnd], "\n") + 1
+
+	if lines == 0 && columns == 0 && newEnd == oldEnd {
+		return
+	}
+	parser.Inspect(root, func(node *parser.ASTNode) bool {
+		// Nothing in a node that ends before the edit moves.
+		if node == unit || (node.Span.End.Line != 0 && node.Span.End.Offset < oldEnd) {
+			return false
+		}
+		for _, pos := range []*parser.Position{&node.Span.Start, &node.Span.End} {
+			if pos.Line == 0 || pos.Offset < oldEnd {
+				continue
+			}
+			if pos.Line == oldLine {
+				pos.Column += columns
+			}
+			pos.Line += lines
+			pos.Offset += newEnd - oldEnd
+		}
+		return true
+	})
+}
@@ -0,0 +1,169 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const incrementalProgram = `glob { g }
+proc {
+	p(a) { local { b } b = a; g = b }
+	q(a) { local { } p(a) }
+}
+func {
+	f(n) { local { } n = (n plus 1); return n }
+}
+main {
+	var { x }
+	x = f(g);
+	q(x);
+	print x
+}`
+
+// sameTree reports where two trees differ in shape, names or spans.
+func sameTree(x, y *parser.ASTNode) error {
+	if x.Type != y.Type || x.Name != y.Name || x.Span != y.Span || len(x.Children) != len(y.Children) {
+		return fmt.Errorf("%s %q at %+v differs from %s %q at %+v", x.Type, x.Name, x.Span, y.Type, y.Name, y.Span)
+	}
+	for i := range x.Children {
+		if err := sameTree(x.Children[i], y.Children[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestIncremental(t *testing.T) {
+	tests := []struct {
+		name   string
+		old    string
+		new    string
+		reused bool
+	}{
+		{"Testing an edit inside a procedure", "b = a;", "b = (a plus 2);", true},
+		{"Testing a new local in a procedure", "local { b } b", "local { b c } c = 1; b", true},
+		{"Testing an edit inside a function", "(n plus 1)", "(n mult\n\t\t3)", true},
+		{"Testing an edit inside main", "print x", "print x;\n\tx = f(x);\n\tp(x)", true},
+		{"Testing a new line between sections", "}\nfunc", "}\n\nfunc", false},
+		{"Testing a new global", "glob { g }", "glob { g h }", false},
+		{"Testing a renamed procedure", "q(a) {", "r(a) {", false},
+		{"Testing a call to a later procedure", "b = a;", "q(a);", false},
+		{"Testing an undeclared variable", "print x", "print y", false},
+		{"Testing a type error", "return n", "return (n > 1)", false},
+		{"Testing a syntax error", "b = a;", "b = ;", false},
+		{"Testing a recursive call", "n = (n plus 1)", "n = f(n)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inc := NewIncremental(incrementalProgram)
+			source := strings.Replace(incrementalProgram, tt.old, tt.new, 1)
+			got := inc.Update(source)
+			if inc.Reused != tt.reused {
+				t.Errorf("expected Reused %v, got %v", tt.reused, inc.Reused)
+			}
+
+			want := Analyse(source)
+			if fmt.Sprint(got.Diagnostics) != fmt.Sprint(want.Diagnostics) {
+				t.Fatalf("expected diagnostics %v, got %v", want.Diagnostics, got.Diagnostics)
+			}
+			if want.Root == nil {
+				return
+			}
+			if err := sameTree(got.Root, want.Root); err != nil {
+				t.Fatal(err)
+			}
+			gotBindings, wantBindings := got.bindings(), want.bindings()
+			for i := range wantBindings {
+				if gotBindings[i].declaration != wantBindings[i].declaration {
+					t.Errorf("expected %s at %+v to refer to identifier %d, got %d", wantBindings[i].node.Name,
+						wantBindings[i].node.Span.Start, wantBindings[i].declaration, gotBindings[i].declaration)
+				}
+				gotSymbol, _ := got.Describe(gotBindings[i].node)
+				wantSymbol, _ := want.Describe(wantBindings[i].node)
+				if gotSymbol.Kind != wantSymbol.Kind || gotSymbol.Type != wantSymbol.Type {
+					t.Errorf("expected %s to be %+v, got %+v", wantBindings[i].node.Name, wantSymbol, gotSymbol)
+				}
+			}
+		})
+	}
+}
+
+func TestIncrementalEdits(t *testing.T) {
+	inc := NewIncremental(incrementalProgram)
+	source := incrementalProgram
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 {
+			source = strings.Replace(source, "print x", fmt.Sprintf("x = (x plus %d);\n\tprint x", i), 1)
+		} else {
+			source = strings.Replace(source, "g = b", fmt.Sprintf("b = (b mult %d);\n\t\tg = b", i), 1)
+		}
+		inc.Update(source)
+		if !inc.Reused {
+			t.Fatalf("expected edit %d to reuse the rest of the program", i)
+		}
+	}
+	got, want := inc.Analysis(), Analyse(source)
+	if len(got.Diagnostics) != 0 || len(want.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v, %v", got.Diagnostics, want.Diagnostics)
+	}
+	if err := sameTree(got.Root, want.Root); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// benchmarkProgram generates a program of about the given number of
+// lines. It has few names, since every declaration and call takes one of
+// the unique names scoping hands out.
+func benchmarkProgram(lines int) string {
+	const procs = 20
+	var b strings.Builder
+	b.WriteString("glob { g }\nproc {\n")
+	for i := 0; i < procs; i++ {
+		fmt.Fprintf(&b, "\tp%d(a) {\n\t\tlocal { c }\n\t\tc = a;\n", i)
+		for j := 0; j < lines/procs/2; j++ {
+			fmt.Fprintf(&b, "\t\tc = ((c plus a) mult %d);\n", j)
+			b.WriteString("\t\tif (c > g) { g = (g minus 1) } else { c = (c div 2) };\n")
+		}
+		b.WriteString("\t\tg = c\n\t}\n")
+	}
+	b.WriteString("}\nfunc { }\nmain {\n\tvar { x }\n\tx = 1;\n")
+	for i := 0; i < procs; i++ {
+		fmt.Fprintf(&b, "\tp%d(x);\n", i)
+	}
+	b.WriteString("\tprint g\n}\n")
+	return b.String()
+}
+
+func BenchmarkFullAnalysis(b *testing.B) {
+	source := benchmarkProgram(5000)
+	edited := strings.Replace(source, "c = ((c plus a) mult 0)", "c = ((c plus a) mult 7)", 1)
+	for i := 0; i < b.N; i++ {
+		if i%2 == 0 {
+			Analyse(edited)
+		} else {
+			Analyse(source)
+		}
+	}
+}
+
+func BenchmarkIncrementalEdit(b *testing.B) {
+	source := benchmarkProgram(5000)
+	edited := strings.Replace(source, "c = ((c plus a) mult 0)", "c = ((c plus a) mult 7)", 1)
+	inc := NewIncremental(source)
+	if len(inc.Analysis().Diagnostics) != 0 {
+		b.Fatal(inc.Analysis().Diagnostics)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%2 == 0 {
+			inc.Update(edited)
+		} else {
+			inc.Update(source)
+		}
+		if !inc.Reused {
+			b.Fatal("expected the edit to reuse the rest of the program")
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+	"strings"
+)
+
+func ValidateTranslateToJavaScript(root *parser.ASTNode) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return TranslateToJavaScript(root), nil
+}
+
+// TranslateToJavaScript emits an ES module exporting run(print). Every call
+// to run executes the program from scratch and passes each printed line to
+// the print callback. halt throws the exported HALT sentinel, which run
+// catches. Numbers are BigInts so that div truncates like the other backends.
+func TranslateToJavaScript(root *parser.ASTNode) string {
+	output := []string{
+		"// SPL program translated to JavaScript",
+		"export const HALT = Object.freeze({ halt: true });",
+		"",
+		"export function run(print) {",
+	}
+
+	for _, v := range root.Children[0].Children {
+		output = append(output, fmt.Sprintf("\tlet %s = 0n; // %s", getVar(v), v.Name))
+	}
+
+	for _, def := range definitions(root) {
+		kind := "proc"
+		if def.Type == FDEF {
+			kind = "func"
+		}
+		params := make([]string, 0)
+		for _, param := range parameterList(def) {
+			params = append(params, getVar(param))
+		}
+		name := symbolTable[int(def.Children[0].ID)].uniqueID
+		output = append(output,
+			"",
+			fmt.Sprintf("\t// %s %s", kind, def.Children[0].Name),
+			fmt.Sprintf("\tfunction %s(%s) {", name, strings.Join(params, ", ")))
+		output = append(output, jsLocals(localList(def), 2)...)
+		output = append(output, jsAlgo(def.Children[2].Children[1], 2)...)
+		if def.Type == FDEF {
+			output = append(output, fmt.Sprintf("\t\treturn %s;", jsAtom(def.Children[3])))
+		}
+		output = append(output, "\t}")
+	}
+
+	mainProg := root.Children[3]
+	output = append(output, "", "\ttry {")
+	output = append(output, jsLocals(mainProg.Children[0].Children, 2)...)
+	output = append(output, jsAlgo(mainProg.Children[1], 2)...)
+	output = append(output,
+		"\t} catch (caught) {",
+		"\t\tif (caught !== HALT) {",
+		"\t\t\tthrow caught;",
+		"\t\t}",
+		"\t}",
+		"}",
+		"")
+	return strings.Join(output, "\n")
+}
+
+func jsLocals(vars []*parser.ASTNode, depth int) []string {
+	output := make([]string, 0)
+	for _, v := range vars {
+		output = append(output, fmt.Sprintf("%slet %s = 0n; // %s", cIndent(depth), getVar(v), v.Name))
+	}
+	return output
+}
+
+func jsAlgo(node *parser.ASTNode, depth int) []string {
+	output := make([]string, 0)
+	for _, instr := range node.Children {
+		output = append(output, jsInstr(instr, depth)...)
+	}
+	return output
+}
+
+func jsInstr(node *parser.ASTNode, depth int) []string {
+	indent := cIndent(depth)
+	switch node.Name {
+	case "halt":
+		return []string{indent + "throw HALT;"}
+	case "print":
+		output := node.Children[0]
+		if len(output.Children) > 0 {
+			return []string{fmt.Sprintf("%sprint(String(%s));", indent, jsAtom(output.Children[0]))}
+		}
+		return []string{fmt.Sprintf("%sprint(%s);", indent, jsString(output.Name))}
+	case "call":
+		name := declaredUniqueID(node.Children[0])
+		return []string{fmt.Sprintf("%s%s(%s);", indent, name, jsArgs(node.Children[1]))}
+	case "assign":
+		assign := node.Children[0]
+		vname := getVar(assign.Children[0])
+		if assign.Name == "call" {
+			name := declaredUniqueID(assign.Children[1])
+			return []string{fmt.Sprintf("%s%s = %s(%s);", indent, vname, name, jsArgs(assign.Children[2]))}
+		}
+		return []string{fmt.Sprintf("%s%s = %s;", indent, vname, jsTerm(assign.Children[1]))}
+	case "loop":
+		loop := node.Children[0]
+		switch loop.Name {
+		case "while":
+			output := []string{fmt.Sprintf("%swhile (%s) {", indent, jsTerm(loop.Children[0]))}
+			output = append(output, jsAlgo(loop.Children[1], depth+1)...)
+			return append(output, indent+"}")
+		case "do":
+			output := []string{indent + "do {"}
+			output = append(output, jsAlgo(loop.Children[0], depth+1)...)
+			return append(output, fmt.Sprintf("%s} while (!%s);", indent, jsTerm(loop.Children[1])))
+		default:
+			panic("expected 'while' or 'do' Loop node name")
+		}
+	case "branch":
+		branch := node.Children[0]
+		output := []string{fmt.Sprintf("%sif (%s) {", indent, jsTerm(branch.Children[0]))}
+		output = append(output, jsAlgo(branch.Children[1], depth+1)...)
+		switch branch.Name {
+		case "if":
+		case "ifelse":
+			output = append(output, indent+"} else {")
+			output = append(output, jsAlgo(branch.Children[2], depth+1)...)
+		default:
+			panic("expected 'if' or 'ifelse' Branch node name")
+		}
+		return append(output, indent+"}")
+	default:
+		panic("expected 'halt', 'print', 'call', 'assign', 'loop' or 'branch' Instr node name")
+	}
+}
+
+func jsArgs(input *parser.ASTNode) string {
+	args := make([]string, 0)
+	for _, atom := range input.Children {
+		args = append(args, jsAtom(atom))
+	}
+	return strings.Join(args, ", ")
+}
+
+func jsTerm(node *parser.ASTNode) string {
+	switch node.Name {
+	case "atom":
+		return jsAtom(node.Children[0])
+	case "unop":
+		switch node.Children[0].Name {
+		case "neg":
+			return fmt.Sprintf("(-%s)", jsTerm(node.Children[1]))
+		case "not":
+			return fmt.Sprintf("(!%s)", jsTerm(node.Children[1]))
+		default:
+			panic("expected 'neg' or 'not' UnOp node name")
+		}
+	case "binop":
+		op := cBinOp(node.Children[1])
+		if op == "==" {
+			op = "==="
+		}
+		return fmt.Sprintf("(%s %s %s)", jsTerm(node.Children[0]), op, jsTerm(node.Children[2]))
+	default:
+		panic("expected 'atom', 'unop', or 'binop' Term node name")
+	}
+}
+
+func jsAtom(node *parser.ASTNode) string {
+	if len(node.Children) > 0 {
+		return getVar(node.Children[0])
+	}
+	return node.Name + "n"
+}
+
+func jsString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if r >= 0x20 && r < 0x7f && r != '"' && r != '\\' {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "\\u{%x}", r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
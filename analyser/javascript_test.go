@@ -0,0 +1,44 @@
+package analyser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslateToJavaScript(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not available")
+	}
+	for _, tt := range backendPrograms {
+		t.Run(tt.name, func(t *testing.T) {
+			root := analyseForTest(t, tt.input)
+			code, err := ValidateTranslateToJavaScript(root)
+			if err != nil {
+				t.Fatalf("translate: %v", err)
+			}
+
+			dir := t.TempDir()
+			module := filepath.Join(dir, "prog.mjs")
+			driver := filepath.Join(dir, "driver.mjs")
+			if err := os.WriteFile(module, []byte(code), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			runner := `import { run } from "./prog.mjs";
+run((line) => process.stdout.write(line + "\n"));
+`
+			if err := os.WriteFile(driver, []byte(runner), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			out, err := exec.Command(node, driver).CombinedOutput()
+			if err != nil {
+				t.Fatalf("node: %v\n%s\n%s", err, out, code)
+			}
+			if string(out) != tt.expected {
+				t.Errorf("expected output %q, got %q\n%s", tt.expected, out, code)
+			}
+		})
+	}
+}
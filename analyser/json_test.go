@@ -0,0 +1,92 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// analyseTree runs the analysis phases on a tree that was not parsed from
+// source and returns the first error.
+func analyseTree(root *parser.ASTNode) error {
+	if err := ValidateScoping(root); err != nil {
+		return fmt.Errorf("scoping: %v", err)
+	}
+	if err := ValidateTypeChecking(root); err != nil {
+		return fmt.Errorf("type checking: %v", err)
+	}
+	if err := ValidateNoRecursion(root); err != nil {
+		return fmt.Errorf("recursion: %v", err)
+	}
+	return nil
+}
+
+func TestAnalyseJSONAST(t *testing.T) {
+	root, err := parser.Validate(`glob { g }
+proc {
+	p(a) { local { } g = a }
+}
+func {
+	f(n) { local { } n = (n plus 1); return n }
+}
+main {
+	var { x }
+	x = f(2);
+	p(x);
+	print g
+}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	data, err := parser.MarshalAST(root)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	decoded, err := parser.UnmarshalAST(data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	program := make([][]string, 0)
+	for _, tree := range []*parser.ASTNode{root, decoded} {
+		if err := analyseTree(tree); err != nil {
+			t.Fatal(err)
+		}
+		program = append(program, GenerateProgram(tree))
+	}
+	if !reflect.DeepEqual(program[0], program[1]) {
+		t.Errorf("the decoded tree generates\n%v\nthe parsed one\n%v", program[1], program[0])
+	}
+}
+
+func TestAnalyseJSONASTMalformed(t *testing.T) {
+	const sections = `{"id": 2, "type": "VARIABLES"}, {"id": 3, "type": "PROCDEFS"}, {"id": 4, "type": "FUNCDEFS"}`
+	tests := []struct {
+		name     string
+		root     string
+		expected string
+	}{
+		{"Testing a program with two sections", `{"id": 1, "type": "SPL_PROG", "children": [
+			{"id": 2, "type": "VARIABLES"}, {"id": 3, "type": "PROCDEFS"}]}`, "expected SPL_PROG with 4 children"},
+		{"Testing an unknown kind of branch", `{"id": 1, "type": "SPL_PROG", "children": [` + sections + `,
+			{"id": 5, "type": "MAINPROG", "children": [{"id": 6, "type": "VARIABLES"},
+				{"id": 7, "type": "ALGO", "children": [{"id": 8, "type": "INSTR", "name": "branch", "children": [
+					{"id": 9, "type": "BRANCH", "name": "maybe", "children": [
+						{"id": 10, "type": "TERM", "name": "atom", "children": [{"id": 11, "type": "ATOM", "name": "1"}]},
+						{"id": 12, "type": "ALGO"}, {"id": 13, "type": "ALGO"}]}]}]}]}]}`, `unknown branch "maybe"`},
+		{"Testing a main program without instructions", `{"id": 1, "type": "SPL_PROG", "children": [` + sections + `,
+			{"id": 5, "type": "MAINPROG", "children": [{"id": 6, "type": "VARIABLES"}]}]}`, "expected MAINPROG with 2 children"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The decoder rejects trees the parser cannot produce, so they
+			// never reach the analyser.
+			_, err := parser.UnmarshalAST([]byte(`{"schema": "spl-ast/1", "root": ` + tt.root + `}`))
+			if err == nil || !strings.Contains(err.Error(), "invalid AST shape: ") || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("got error %v, expected a shape error containing %q", err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package analyser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LineNumbering controls how BASIC lines are numbered. A Max of zero leaves
+// only the dialect's own limit, if any.
+type LineNumbering struct {
+	Start     int
+	Increment int
+	Max       int
+}
+
+var DefaultLineNumbering = LineNumbering{Start: 10, Increment: 10}
+
+// assign returns the numbers for count lines, panicking when the last one
+// would exceed either Max or the dialect limit.
+func (n LineNumbering) assign(count, dialectMax int) []int {
+	if n.Start < 0 {
+		panic(fmt.Sprintf("start line %d is negative", n.Start))
+	}
+	if n.Increment < 1 {
+		panic(fmt.Sprintf("line increment %d must be at least 1", n.Increment))
+	}
+	limit := n.Max
+	if dialectMax > 0 && (limit == 0 || dialectMax < limit) {
+		limit = dialectMax
+	}
+
+	numbers := make([]int, count)
+	for i := range numbers {
+		numbers[i] = n.Start + i*n.Increment
+		if limit > 0 && numbers[i] > limit {
+			panic(fmt.Sprintf("%d lines do not fit: line number %d exceeds the limit of %d", count, numbers[i], limit))
+		}
+	}
+	return numbers
+}
+
+// numberLines prefixes every line with its number. Aligned numbers are
+// padded to the width of the largest one, but never narrower than the
+// three digits the translation has always used.
+func numberLines(lines []string, numbers []int, align bool) []string {
+	width := 0
+	if align {
+		width = 3
+		for _, number := range numbers {
+			width = max(width, len(strconv.Itoa(number)))
+		}
+	}
+	output := make([]string, len(lines))
+	for i, line := range lines {
+		output[i] = fmt.Sprintf("%-*d %s", width, numbers[i], line)
+	}
+	return output
+}
+
+func ValidateRenumberBasic(lines []string, numbering LineNumbering, align bool) (output []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return RenumberBasic(lines, numbering, align), nil
+}
+
+// RenumberBasic renumbers a BASIC program and rewrites the line numbers
+// after GOTO, GOSUB, THEN and ELSE, including ON ... GOTO lists. String
+// literals and REM comments are left alone. Blank lines are dropped.
+func RenumberBasic(lines []string, numbering LineNumbering, align bool) []string {
+	oldNumbers := make([]int, 0)
+	statements := make([]string, 0)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		end := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsDigit(r) })
+		if end == -1 {
+			end = len(line)
+		}
+		number, err := strconv.Atoi(line[:end])
+		if err != nil {
+			panic(fmt.Sprintf("line without a line number: %q", line))
+		}
+		if len(oldNumbers) > 0 && number <= oldNumbers[len(oldNumbers)-1] {
+			panic(fmt.Sprintf("line %d does not come after line %d", number, oldNumbers[len(oldNumbers)-1]))
+		}
+		oldNumbers = append(oldNumbers, number)
+		statements = append(statements, strings.TrimSpace(line[end:]))
+	}
+
+	newNumbers := numbering.assign(len(statements), 0)
+	renumbered := make(map[int]int, len(oldNumbers))
+	for i, number := range oldNumbers {
+		renumbered[number] = newNumbers[i]
+	}
+	for i, statement := range statements {
+		statements[i] = renumberTargets(statement, oldNumbers[i], renumbered)
+	}
+	return numberLines(statements, newNumbers, align)
+}
+
+// isNameByte reports whether a byte can be part of a BASIC name or keyword.
+func isNameByte(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c == '%'
+}
+
+func renumberTargets(statement string, line int, renumbered map[int]int) string {
+	var b strings.Builder
+	upper := strings.ToUpper(statement)
+	inString := false
+	for i := 0; i < len(statement); {
+		if statement[i] == '"' {
+			inString = !inString
+		}
+		if inString {
+			b.WriteByte(statement[i])
+			i++
+			continue
+		}
+		// Keywords only start where a name cannot continue, so PREMIUM
+		// holds no REM and NOTHEN no THEN.
+		if i > 0 && isNameByte(upper[i-1]) {
+			b.WriteByte(statement[i])
+			i++
+			continue
+		}
+		if strings.HasPrefix(upper[i:], "REM") {
+			b.WriteString(statement[i:])
+			break
+		}
+
+		keyword := ""
+		for _, candidate := range []string{"GOTO", "GOSUB", "THEN", "ELSE"} {
+			// A target may follow without a space, a name may not.
+			if after := i + len(candidate); strings.HasPrefix(upper[i:], candidate) &&
+				(after == len(upper) || !isNameByte(upper[after]) || unicode.IsDigit(rune(upper[after]))) {
+				keyword = candidate
+			}
+		}
+		if keyword == "" {
+			b.WriteByte(statement[i])
+			i++
+			continue
+		}
+
+		b.WriteString(statement[i : i+len(keyword)])
+		i += len(keyword)
+		for {
+			start := i
+			for i < len(statement) && statement[i] == ' ' {
+				i++
+			}
+			end := i
+			for end < len(statement) && unicode.IsDigit(rune(statement[end])) {
+				end++
+			}
+			if end == i {
+				i = start
+				break
+			}
+			target, _ := strconv.Atoi(statement[i:end])
+			number, ok := renumbered[target]
+			if !ok {
+				panic(fmt.Sprintf("line %d: %s to line %d which does not exist", line, keyword, target))
+			}
+			fmt.Fprintf(&b, "%s%d", statement[start:i], number)
+			i = end
+			if keyword == "THEN" || keyword == "ELSE" || i >= len(statement) || statement[i] != ',' {
+				break
+			}
+			b.WriteByte(',')
+			i++
+		}
+	}
+	return b.String()
+}
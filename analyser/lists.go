@@ -0,0 +1,31 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+)
+
+// definitions returns the PDEF nodes followed by the FDEF nodes of a
+// program.
+func definitions(root *parser.ASTNode) []*parser.ASTNode {
+	procs := root.Children[1].Children
+	funcs := root.Children[2].Children
+	output := make([]*parser.ASTNode, 0, len(procs)+len(funcs))
+	return append(append(output, procs...), funcs...)
+}
+
+// parameterList returns the VAR nodes declared by a PDEF or FDEF.
+func parameterList(def *parser.ASTNode) []*parser.ASTNode {
+	return def.Children[1].Children[0].Children
+}
+
+// localList returns the VAR nodes declared in the local section of a PDEF or FDEF.
+func localList(def *parser.ASTNode) []*parser.ASTNode {
+	return def.Children[2].Children[0].Children
+}
+
+// declaredUniqueID returns the unique name of the procedure or function a
+// NAME node refers to. Call sites get their own symbol table entry, so the
+// name has to be taken from the declaration.
+func declaredUniqueID(node *parser.ASTNode) string {
+	return symbolTable[symbolTable[int(node.ID)].declarationNode].uniqueID
+}
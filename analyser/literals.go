@@ -0,0 +1,23 @@
+package analyser
+
+import "math/big"
+
+// The intermediate code writes numbers as decimal literals and everything
+// else an operand can be as a name, so the backends tell them apart by the
+// first character.
+
+// isNumber reports whether an operand of the intermediate code is a
+// number.
+func isNumber(value string) bool {
+	return value != "" && value[0] >= '0' && value[0] <= '9'
+}
+
+// numberLiteral converts an SPL number to the 64-bit integers used by the
+// native backends. SPL numbers are unbounded, so larger literals are rejected.
+func numberLiteral(literal string) int64 {
+	n, ok := new(big.Int).SetString(literal, 10)
+	if !ok || !n.IsInt64() {
+		panic("number out of range for 64-bit integers: " + literal)
+	}
+	return n.Int64()
+}
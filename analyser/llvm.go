@@ -0,0 +1,328 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+	"strings"
+)
+
+type llvmGenerator struct {
+	body    []string
+	strs    []string
+	temps   int
+	labels  int
+	globals map[string]bool
+}
+
+func ValidateTranslateToLLVM(root *parser.ASTNode) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return TranslateToLLVM(root), nil
+}
+
+// TranslateToLLVM emits textual LLVM IR with one function per procedure and
+// function. Every SPL variable lives in an alloca (or a global), so the
+// output is easy to read and mem2reg turns it into SSA when optimising.
+// Pointers use the opaque `ptr` type of LLVM 15 and later.
+func TranslateToLLVM(root *parser.ASTNode) string {
+	g := &llvmGenerator{globals: make(map[string]bool)}
+
+	header := []string{
+		"; SPL program translated to LLVM IR",
+		`@.fmt.int = private unnamed_addr constant [6 x i8] c"%lld\0A\00"`,
+		`@.fmt.str = private unnamed_addr constant [4 x i8] c"%s\0A\00"`,
+	}
+	globals := make([]string, 0)
+	for _, v := range root.Children[0].Children {
+		g.globals[getVar(v)] = true
+		globals = append(globals, fmt.Sprintf("@%s = internal global i64 0 ; %s", getVar(v), v.Name))
+	}
+
+	functions := make([]string, 0)
+	for _, def := range root.Children[1].Children {
+		functions = append(functions, g.function(def)...)
+	}
+	for _, def := range root.Children[2].Children {
+		functions = append(functions, g.function(def)...)
+	}
+
+	mainProg := root.Children[3]
+	g.reset()
+	g.emitLabel("entry")
+	g.allocaLocals(mainProg.Children[0].Children)
+	g.algo(mainProg.Children[1])
+	g.emit("ret i32 0")
+	functions = append(functions, "define i32 @main() {")
+	functions = append(functions, g.body...)
+	functions = append(functions, "}", "")
+
+	output := append(header, g.strs...)
+	output = append(output, globals...)
+	output = append(output,
+		"",
+		"declare i32 @printf(ptr, ...)",
+		"declare void @exit(i32)",
+		"",
+	)
+	output = append(output, functions...)
+	return strings.Join(output, "\n")
+}
+
+func (g *llvmGenerator) reset() {
+	g.body = make([]string, 0)
+	g.temps = 0
+}
+
+func (g *llvmGenerator) emit(format string, args ...any) {
+	g.body = append(g.body, "  "+fmt.Sprintf(format, args...))
+}
+
+func (g *llvmGenerator) emitLabel(label string) {
+	g.body = append(g.body, label+":")
+}
+
+func (g *llvmGenerator) newTemp() string {
+	g.temps++
+	return fmt.Sprintf("%%t.%d", g.temps)
+}
+
+func (g *llvmGenerator) newLabel(prefix string) string {
+	g.labels++
+	return fmt.Sprintf("%s.%d", prefix, g.labels)
+}
+
+func (g *llvmGenerator) function(def *parser.ASTNode) []string {
+	g.reset()
+	g.emitLabel("entry")
+
+	params := make([]string, 0)
+	for _, param := range parameterList(def) {
+		name := getVar(param)
+		params = append(params, fmt.Sprintf("i64 %%%s.arg", name))
+		g.emit("%%%s = alloca i64", name)
+		g.emit("store i64 %%%s.arg, ptr %%%s", name, name)
+	}
+	g.allocaLocals(localList(def))
+	g.algo(def.Children[2].Children[1])
+
+	returnType := "void"
+	kind := "proc"
+	if def.Type == FDEF {
+		returnType = "i64"
+		kind = "func"
+		g.emit("ret i64 %s", g.atom(def.Children[3]))
+	} else {
+		g.emit("ret void")
+	}
+
+	name := symbolTable[int(def.Children[0].ID)].uniqueID
+	output := []string{
+		fmt.Sprintf("; %s %s", kind, def.Children[0].Name),
+		fmt.Sprintf("define internal %s @%s(%s) {", returnType, name, strings.Join(params, ", ")),
+	}
+	output = append(output, g.body...)
+	return append(output, "}", "")
+}
+
+func (g *llvmGenerator) allocaLocals(vars []*parser.ASTNode) {
+	for _, v := range vars {
+		g.emit("%%%s = alloca i64 ; %s", getVar(v), v.Name)
+		g.emit("store i64 0, ptr %%%s", getVar(v))
+	}
+}
+
+func (g *llvmGenerator) pointer(name string) string {
+	if g.globals[name] {
+		return "@" + name
+	}
+	return "%" + name
+}
+
+func (g *llvmGenerator) algo(node *parser.ASTNode) {
+	for _, instr := range node.Children {
+		g.instr(instr)
+	}
+}
+
+func (g *llvmGenerator) instr(node *parser.ASTNode) {
+	switch node.Name {
+	case "halt":
+		g.emit("call void @exit(i32 0)")
+		g.emit("unreachable")
+		g.emitLabel(g.newLabel("halted"))
+	case "print":
+		output := node.Children[0]
+		if len(output.Children) > 0 {
+			value := g.atom(output.Children[0])
+			g.emit("call i32 (ptr, ...) @printf(ptr @.fmt.int, i64 %s)", value)
+			return
+		}
+		g.emit("call i32 (ptr, ...) @printf(ptr @.fmt.str, ptr %s)", g.stringConstant(output.Name))
+	case "call":
+		g.call("void", node.Children[0], node.Children[1])
+	case "assign":
+		assign := node.Children[0]
+		var value string
+		if assign.Name == "call" {
+			value = g.call("i64", assign.Children[1], assign.Children[2])
+		} else {
+			value = g.term(assign.Children[1])
+		}
+		g.emit("store i64 %s, ptr %s", value, g.pointer(getVar(assign.Children[0])))
+	case "loop":
+		g.loop(node.Children[0])
+	case "branch":
+		g.branch(node.Children[0])
+	default:
+		panic("expected 'halt', 'print', 'call', 'assign', 'loop' or 'branch' Instr node name")
+	}
+}
+
+func (g *llvmGenerator) call(returnType string, name, input *parser.ASTNode) string {
+	args := make([]string, 0)
+	for _, atom := range input.Children {
+		args = append(args, "i64 "+g.atom(atom))
+	}
+	callee := declaredUniqueID(name)
+	if returnType == "void" {
+		g.emit("call void @%s(%s)", callee, strings.Join(args, ", "))
+		return ""
+	}
+	result := g.newTemp()
+	g.emit("%s = call %s @%s(%s)", result, returnType, callee, strings.Join(args, ", "))
+	return result
+}
+
+func (g *llvmGenerator) loop(node *parser.ASTNode) {
+	switch node.Name {
+	case "while":
+		labelCond := g.newLabel("while.cond")
+		labelBody := g.newLabel("while.body")
+		labelExit := g.newLabel("while.end")
+		g.emit("br label %%%s", labelCond)
+		g.emitLabel(labelCond)
+		cond := g.term(node.Children[0])
+		g.emit("br i1 %s, label %%%s, label %%%s", cond, labelBody, labelExit)
+		g.emitLabel(labelBody)
+		g.algo(node.Children[1])
+		g.emit("br label %%%s", labelCond)
+		g.emitLabel(labelExit)
+	case "do":
+		labelBody := g.newLabel("do.body")
+		labelExit := g.newLabel("do.end")
+		g.emit("br label %%%s", labelBody)
+		g.emitLabel(labelBody)
+		g.algo(node.Children[0])
+		cond := g.term(node.Children[1])
+		g.emit("br i1 %s, label %%%s, label %%%s", cond, labelExit, labelBody)
+		g.emitLabel(labelExit)
+	default:
+		panic("expected 'while' or 'do' Loop node name")
+	}
+}
+
+func (g *llvmGenerator) branch(node *parser.ASTNode) {
+	labelThen := g.newLabel("if.then")
+	labelExit := g.newLabel("if.end")
+	labelElse := labelExit
+	if node.Name == "ifelse" {
+		labelElse = g.newLabel("if.else")
+	} else if node.Name != "if" {
+		panic("expected 'if' or 'ifelse' Branch node name")
+	}
+
+	cond := g.term(node.Children[0])
+	g.emit("br i1 %s, label %%%s, label %%%s", cond, labelThen, labelElse)
+	g.emitLabel(labelThen)
+	g.algo(node.Children[1])
+	g.emit("br label %%%s", labelExit)
+	if node.Name == "ifelse" {
+		g.emitLabel(labelElse)
+		g.algo(node.Children[2])
+		g.emit("br label %%%s", labelExit)
+	}
+	g.emitLabel(labelExit)
+}
+
+// term returns an i64 value for numeric terms and an i1 value for boolean terms.
+func (g *llvmGenerator) term(node *parser.ASTNode) string {
+	switch node.Name {
+	case "atom":
+		return g.atom(node.Children[0])
+	case "unop":
+		operand := g.term(node.Children[1])
+		result := g.newTemp()
+		switch node.Children[0].Name {
+		case "neg":
+			g.emit("%s = sub i64 0, %s", result, operand)
+		case "not":
+			g.emit("%s = xor i1 %s, true", result, operand)
+		default:
+			panic("expected 'neg' or 'not' UnOp node name")
+		}
+		return result
+	case "binop":
+		left := g.term(node.Children[0])
+		right := g.term(node.Children[2])
+		result := g.newTemp()
+		g.emit("%s = %s %s, %s", result, llvmBinOp(node.Children[1]), left, right)
+		return result
+	default:
+		panic("expected 'atom', 'unop', or 'binop' Term node name")
+	}
+}
+
+func llvmBinOp(node *parser.ASTNode) string {
+	switch node.Name {
+	case "eq":
+		return "icmp eq i64"
+	case ">":
+		return "icmp sgt i64"
+	case "or":
+		return "or i1"
+	case "and":
+		return "and i1"
+	case "plus":
+		return "add i64"
+	case "minus":
+		return "sub i64"
+	case "mult":
+		return "mul i64"
+	case "div":
+		return "sdiv i64"
+	default:
+		panic("expected 'eq', '>', 'or', 'and', 'plus', 'minus', 'mult', or 'div' BinOp node name")
+	}
+}
+
+func (g *llvmGenerator) atom(node *parser.ASTNode) string {
+	if len(node.Children) > 0 {
+		result := g.newTemp()
+		g.emit("%s = load i64, ptr %s", result, g.pointer(getVar(node.Children[0])))
+		return result
+	}
+	return fmt.Sprint(numberLiteral(node.Name))
+}
+
+func (g *llvmGenerator) stringConstant(s string) string {
+	name := fmt.Sprintf("@.str.%d", len(g.strs))
+	g.strs = append(g.strs, fmt.Sprintf(`%s = private unnamed_addr constant [%d x i8] c"%s\00"`,
+		name, len(s)+1, llvmString(s)))
+	return name
+}
+
+func llvmString(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "\\%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package analyser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranslateToLLVM(t *testing.T) {
+	llc, errLlc := exec.LookPath("llc")
+	gcc, errGcc := exec.LookPath("gcc")
+	if errLlc != nil || errGcc != nil {
+		t.Skip("llc or gcc not available")
+	}
+	for _, tt := range backendPrograms {
+		t.Run(tt.name, func(t *testing.T) {
+			root := analyseForTest(t, tt.input)
+			code, err := ValidateTranslateToLLVM(root)
+			if err != nil {
+				t.Fatalf("translate: %v", err)
+			}
+
+			dir := t.TempDir()
+			source := filepath.Join(dir, "prog.ll")
+			assembly := filepath.Join(dir, "prog.s")
+			binary := filepath.Join(dir, "prog")
+			if err := os.WriteFile(source, []byte(code), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			args := []string{"-relocation-model=pic", "-o", assembly, source}
+			out, err := exec.Command(llc, args...).CombinedOutput()
+			if err != nil && strings.Contains(string(out), "-opaque-pointers") {
+				// LLVM 14 only understands ptr behind a flag
+				out, err = exec.Command(llc, append([]string{"-opaque-pointers"}, args...)...).CombinedOutput()
+			}
+			if err != nil {
+				t.Fatalf("llc: %v\n%s\n%s", err, out, code)
+			}
+			if out, err := exec.Command(gcc, "-o", binary, assembly).CombinedOutput(); err != nil {
+				t.Fatalf("gcc: %v\n%s", err, out)
+			}
+			output, err := exec.Command(binary).Output()
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if string(output) != tt.expected {
+				t.Errorf("expected output %q, got %q\n%s", tt.expected, output, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+	"strings"
+)
+
+func ValidateTranslateToPython(root *parser.ASTNode) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return TranslateToPython(root), nil
+}
+
+// TranslateToPython emits a self-contained Python 3 script. Identifiers are
+// the uniqueIDs from the symbol table so the output is deterministic, div
+// goes through a helper that truncates towards zero like the other backends
+// and halt calls sys.exit.
+func TranslateToPython(root *parser.ASTNode) string {
+	output := []string{
+		"#!/usr/bin/env python3",
+		"# SPL program translated to Python",
+		"import sys",
+		"",
+		"",
+		"def _div(a, b):",
+		"    q = abs(a) // abs(b)",
+		"    return q if (a >= 0) == (b >= 0) else -q",
+		"",
+		"",
+	}
+
+	globals := make([]string, 0)
+	for _, v := range root.Children[0].Children {
+		globals = append(globals, getVar(v))
+		output = append(output, fmt.Sprintf("%s = 0  # %s", getVar(v), v.Name))
+	}
+	globalDecl := make([]string, 0)
+	if len(globals) > 0 {
+		output = append(output, "", "")
+		globalDecl = append(globalDecl, pyIndent(1)+"global "+strings.Join(globals, ", "))
+	}
+
+	for _, def := range definitions(root) {
+		kind := "proc"
+		if def.Type == FDEF {
+			kind = "func"
+		}
+		params := make([]string, 0)
+		for _, param := range parameterList(def) {
+			params = append(params, getVar(param))
+		}
+		name := symbolTable[int(def.Children[0].ID)].uniqueID
+		output = append(output,
+			fmt.Sprintf("# %s %s", kind, def.Children[0].Name),
+			fmt.Sprintf("def %s(%s):", name, strings.Join(params, ", ")))
+		output = append(output, globalDecl...)
+		output = append(output, pyLocals(localList(def), 1)...)
+		output = append(output, pyAlgo(def.Children[2].Children[1], 1)...)
+		if def.Type == FDEF {
+			output = append(output, fmt.Sprintf("%sreturn %s", pyIndent(1), pyAtom(def.Children[3])))
+		}
+		output = append(output, "", "")
+	}
+
+	mainProg := root.Children[3]
+	output = append(output, "def main():")
+	output = append(output, globalDecl...)
+	output = append(output, pyLocals(mainProg.Children[0].Children, 1)...)
+	output = append(output, pyAlgo(mainProg.Children[1], 1)...)
+	output = append(output,
+		"",
+		"",
+		`if __name__ == "__main__":`,
+		"    main()",
+		"")
+	return strings.Join(output, "\n")
+}
+
+func pyIndent(depth int) string {
+	return strings.Repeat("    ", depth)
+}
+
+func pyLocals(vars []*parser.ASTNode, depth int) []string {
+	output := make([]string, 0)
+	for _, v := range vars {
+		output = append(output, fmt.Sprintf("%s%s = 0  # %s", pyIndent(depth), getVar(v), v.Name))
+	}
+	return output
+}
+
+func pyAlgo(node *parser.ASTNode, depth int) []string {
+	output := make([]string, 0)
+	for _, instr := range node.Children {
+		output = append(output, pyInstr(instr, depth)...)
+	}
+	if len(output) == 0 {
+		output = append(output, pyIndent(depth)+"pass")
+	}
+	return output
+}
+
+func pyInstr(node *parser.ASTNode, depth int) []string {
+	indent := pyIndent(depth)
+	switch node.Name {
+	case "halt":
+		return []string{indent + "sys.exit(0)"}
+	case "print":
+		output := node.Children[0]
+		if len(output.Children) > 0 {
+			return []string{fmt.Sprintf("%sprint(%s)", indent, pyAtom(output.Children[0]))}
+		}
+		return []string{fmt.Sprintf("%sprint(%s)", indent, pyString(output.Name))}
+	case "call":
+		name := declaredUniqueID(node.Children[0])
+		return []string{fmt.Sprintf("%s%s(%s)", indent, name, pyArgs(node.Children[1]))}
+	case "assign":
+		assign := node.Children[0]
+		vname := getVar(assign.Children[0])
+		if assign.Name == "call" {
+			name := declaredUniqueID(assign.Children[1])
+			return []string{fmt.Sprintf("%s%s = %s(%s)", indent, vname, name, pyArgs(assign.Children[2]))}
+		}
+		return []string{fmt.Sprintf("%s%s = %s", indent, vname, pyTerm(assign.Children[1]))}
+	case "loop":
+		loop := node.Children[0]
+		switch loop.Name {
+		case "while":
+			output := []string{fmt.Sprintf("%swhile %s:", indent, pyTerm(loop.Children[0]))}
+			return append(output, pyAlgo(loop.Children[1], depth+1)...)
+		case "do":
+			output := []string{indent + "while True:"}
+			output = append(output, pyAlgo(loop.Children[0], depth+1)...)
+			return append(output,
+				fmt.Sprintf("%sif %s:", pyIndent(depth+1), pyTerm(loop.Children[1])),
+				pyIndent(depth+2)+"break")
+		default:
+			panic("expected 'while' or 'do' Loop node name")
+		}
+	case "branch":
+		branch := node.Children[0]
+		output := []string{fmt.Sprintf("%sif %s:", indent, pyTerm(branch.Children[0]))}
+		output = append(output, pyAlgo(branch.Children[1], depth+1)...)
+		switch branch.Name {
+		case "if":
+		case "ifelse":
+			output = append(output, indent+"else:")
+			output = append(output, pyAlgo(branch.Children[2], depth+1)...)
+		default:
+			panic("expected 'if' or 'ifelse' Branch node name")
+		}
+		return output
+	default:
+		panic("expected 'halt', 'print', 'call', 'assign', 'loop' or 'branch' Instr node name")
+	}
+}
+
+func pyArgs(input *parser.ASTNode) string {
+	args := make([]string, 0)
+	for _, atom := range input.Children {
+		args = append(args, pyAtom(atom))
+	}
+	return strings.Join(args, ", ")
+}
+
+func pyTerm(node *parser.ASTNode) string {
+	switch node.Name {
+	case "atom":
+		return pyAtom(node.Children[0])
+	case "unop":
+		switch node.Children[0].Name {
+		case "neg":
+			return fmt.Sprintf("(-%s)", pyTerm(node.Children[1]))
+		case "not":
+			return fmt.Sprintf("(not %s)", pyTerm(node.Children[1]))
+		default:
+			panic("expected 'neg' or 'not' UnOp node name")
+		}
+	case "binop":
+		left := pyTerm(node.Children[0])
+		right := pyTerm(node.Children[2])
+		switch node.Children[1].Name {
+		case "eq":
+			return fmt.Sprintf("(%s == %s)", left, right)
+		case ">":
+			return fmt.Sprintf("(%s > %s)", left, right)
+		case "or":
+			return fmt.Sprintf("(%s or %s)", left, right)
+		case "and":
+			return fmt.Sprintf("(%s and %s)", left, right)
+		case "plus":
+			return fmt.Sprintf("(%s + %s)", left, right)
+		case "minus":
+			return fmt.Sprintf("(%s - %s)", left, right)
+		case "mult":
+			return fmt.Sprintf("(%s * %s)", left, right)
+		case "div":
+			return fmt.Sprintf("_div(%s, %s)", left, right)
+		default:
+			panic("expected 'eq', '>', 'or', 'and', 'plus', 'minus', 'mult', or 'div' BinOp node name")
+		}
+	default:
+		panic("expected 'atom', 'unop', or 'binop' Term node name")
+	}
+}
+
+func pyAtom(node *parser.ASTNode) string {
+	if len(node.Children) > 0 {
+		return getVar(node.Children[0])
+	}
+	return node.Name
+}
+
+func pyString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r >= 0x20 && r < 0x7f && r != '"' && r != '\\':
+			b.WriteRune(r)
+		case r <= 0xffff:
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			fmt.Fprintf(&b, "\\U%08x", r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
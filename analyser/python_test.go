@@ -0,0 +1,36 @@
+package analyser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslateToPython(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+	for _, tt := range backendPrograms {
+		t.Run(tt.name, func(t *testing.T) {
+			root := analyseForTest(t, tt.input)
+			code, err := ValidateTranslateToPython(root)
+			if err != nil {
+				t.Fatalf("translate: %v", err)
+			}
+
+			script := filepath.Join(t.TempDir(), "prog.py")
+			if err := os.WriteFile(script, []byte(code), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			out, err := exec.Command(python, script).CombinedOutput()
+			if err != nil {
+				t.Fatalf("python: %v\n%s\n%s", err, out, code)
+			}
+			if string(out) != tt.expected {
+				t.Errorf("expected output %q, got %q\n%s", tt.expected, out, code)
+			}
+		})
+	}
+}
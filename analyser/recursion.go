@@ -3,7 +3,6 @@ package analyser
 import (
 	"errors"
 	"fmt"
-	"slices"
 
 	"SPL-compiler/parser"
 )
@@ -28,95 +27,23 @@ func ValidateNoRecursion(root *parser.ASTNode) (err error) {
 	return nil
 }
 
+// CheckRecursion panics when a procedure or function can call itself,
+// directly or through others. It finds the cycles in the call graph that
+// BuildCallGraph builds, the one `spl dot --callgraph` draws.
 func CheckRecursion(root *parser.ASTNode) {
 	rootNode = root
-	procdefs := root.Children[1]
-	funcdefs := root.Children[2]
-
-	for len(procdefs.Children) > 0 {
-		if checkDefForRecursion(
-			procdefs.Children[0],
-			[]string{symbolTable[int(procdefs.Children[0].Children[0].ID)].symbolName},
-		) {
+	graph := BuildCallGraph(root)
+	for _, def := range root.Children[1].Children {
+		visiting = def
+		if graph.Cyclic(def) {
 			panic("Recursion detected in procedure definitions")
 		}
-		procdefs = procdefs.Children[1]
 	}
 
-	for len(funcdefs.Children) > 0 {
-		if checkDefForRecursion(
-			funcdefs.Children[0],
-			[]string{symbolTable[int(funcdefs.Children[0].Children[0].ID)].symbolName},
-		) {
+	for _, def := range root.Children[2].Children {
+		visiting = def
+		if graph.Cyclic(def) {
 			panic("Recursion detected in function definitions")
 		}
-		funcdefs = funcdefs.Children[1]
-	}
-}
-
-func checkDefForRecursion(node *parser.ASTNode, names []string) bool {
-	if node.Type == "FDEF" || node.Type == "PFDEF" {
-		body := node.Children[2]
-		algo := body.Children[1]
-		if checkAlgoForRecursion(algo, names) {
-			return true
-		}
-	}
-	return false
-}
-
-func checkAlgoForRecursion(node *parser.ASTNode, names []string) bool {
-	for len(node.Children) > 1 {
-		instr := node.Children[0]
-		switch instr.Name {
-		case "call":
-			calledName := instr.Children[0]
-			if slices.Contains(names, calledName.Name) {
-				return true
-			}
-			nameDefID := symbolTable[int(calledName.ID)].declarationNode
-			nameDefNode := parser.GetNodeByID(rootNode, nameDefID)
-			if checkDefForRecursion(nameDefNode, append(names, calledName.Name)) {
-				return true
-			}
-		case "assign":
-			if checkAssignForRecursion(instr.Children[0], names) {
-				return true
-			}
-		}
-		node = node.Children[1]
-	}
-	instr := node.Children[0]
-	switch instr.Name {
-	case "call":
-		calledName := instr.Children[0]
-		if slices.Contains(names, calledName.Name) {
-			return true
-		}
-		nameDefID := symbolTable[int(calledName.ID)].declarationNode
-		nameDefNode := parser.GetNodeByID(rootNode, nameDefID)
-		if checkDefForRecursion(nameDefNode, append(names, calledName.Name)) {
-			return true
-		}
-	case "assign":
-		if checkAssignForRecursion(instr.Children[0], names) {
-			return true
-		}
-	}
-	return false
-}
-
-func checkAssignForRecursion(node *parser.ASTNode, names []string) bool {
-	if node.Name == "call" {
-		calledName := node.Children[1]
-		if slices.Contains(names, calledName.Name) {
-			return true
-		}
-		nameDefID := symbolTable[int(calledName.ID)].declarationNode
-		nameDefNode := parser.GetNodeByID(rootNode, nameDefID)
-		if checkDefForRecursion(nameDefNode, append(names, calledName.Name)) {
-			return true
-		}
 	}
-	return false
 }
@@ -42,3 +42,78 @@ func testRecursion(ast *parser.ASTNode) {
 	CheckRecursion(ast)
 	PrettyPrintSymbolTable(symbolTable)
 }
+
+func TestCheckRecursion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		prepare  func(root *parser.ASTNode)
+		expected string
+	}{
+		{"Testing a function calling itself in a loop", `glob { }
+proc { }
+func {
+	f(n) { local { } while (n > 0) { n = f(n) }; return n }
+}
+main { var { } halt }`, nil, "Recursion detected in function definitions"},
+		{"Testing a procedure calling itself in a branch", `glob { }
+proc {
+	p(a) { local { } if (a > 0) { p(a) } else { halt } }
+}
+func { }
+main { var { } p(1) }`, nil, "Recursion detected in procedure definitions"},
+		// Scoping rejects calls to later definitions, so the cycle p, q, p
+		// is made by pointing the call in p at q afterwards.
+		{"Testing two procedures calling each other", `glob { }
+proc {
+	p(a) { local { } if (a > 0) { p(a) } else { halt } }
+	q(a) { local { } p(a) }
+}
+func { }
+main { var { } q(1) }`, func(root *parser.ASTNode) {
+			p, q := root.Children[1].Children[0], root.Children[1].Children[1]
+			forEachCall(p.Children[2].Children[1], func(name *parser.ASTNode) {
+				info := symbolTable[int(name.ID)]
+				info.symbolName, info.declarationNode = "q", int(q.Children[0].ID)
+				symbolTable[int(name.ID)] = info
+				name.Name = "q"
+			})
+		}, "Recursion detected in procedure definitions"},
+		{"Testing a function calling a procedure", `glob { }
+proc {
+	p(a) { local { } print a }
+}
+func {
+	f(n) { local { } p(n); return n }
+}
+main { var { x } x = f(1) }`, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parser.Validate(tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if err := ValidateScoping(root); err != nil {
+				t.Fatalf("scoping: %v", err)
+			}
+			if tt.prepare != nil {
+				tt.prepare(root)
+			}
+			err = ValidateNoRecursion(root)
+			if got := fmt.Sprint(err); (tt.expected == "" && err != nil) || (tt.expected != "" && got != tt.expected) {
+				t.Errorf("got error %v, expected %q", err, tt.expected)
+			}
+
+			// The check agrees with the graph spl dot --callgraph draws.
+			graph := BuildCallGraph(root)
+			cyclic := false
+			for _, def := range definitions(root) {
+				cyclic = cyclic || graph.Cyclic(def)
+			}
+			if cyclic != (tt.expected != "") {
+				t.Errorf("the call graph has a cycle: %v, expected %v", cyclic, tt.expected != "")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package analyser
+
+import (
+	"SPL-compiler/lexer"
+	"SPL-compiler/parser"
+	"SPL-compiler/token"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Rename renames the variable, procedure or function that node declares
+// or refers to. It returns the new source and the spans in the old source
+// that were replaced, the declaration first.
+//
+// The rename is refused when name is not an identifier or is a keyword,
+// when the renamed program breaks the naming rules, and when any use of a
+// name would refer to a different declaration afterwards, as happens when
+// the new name is shadowed by or shadows another declaration.
+func (a *Analysis) Rename(node *parser.ASTNode, name string) (string, []parser.Span, error) {
+	references := a.References(node)
+	if references == nil {
+		return "", nil, fmt.Errorf("no variable, procedure or function to rename")
+	}
+	for _, d := range a.Diagnostics {
+		if d.Phase == "syntax" || d.Phase == "naming" {
+			return "", nil, fmt.Errorf("cannot rename in a program with errors: %s", d)
+		}
+	}
+	if tokens := lexer.TokenizeInput(name); len(tokens) != 2 || tokens[0].Literal != name || tokens[0].Type != token.IDENT {
+		if tokens[0].Literal == name && token.LookupIdent(name) != token.IDENT {
+			return "", nil, fmt.Errorf("%s is a keyword", name)
+		}
+		return "", nil, fmt.Errorf("%q is not a valid name", name)
+	}
+	old := references[0].Name
+	// Scoping does not compare procedure names with function names, but
+	// calls could not tell them apart.
+	if references[0].Type == NAME {
+		for _, def := range definitions(a.Root) {
+			if def.Children[0].Name == name && def.Children[0] != references[0] {
+				return "", nil, fmt.Errorf("renaming %s to %s: name-rule-violation: name redeclaration of %s", old, name, name)
+			}
+		}
+	}
+
+	spans := make([]parser.Span, len(references))
+	for i, reference := range references {
+		spans[i] = reference.Span
+	}
+	sorted := slices.Clone(spans)
+	slices.SortFunc(sorted, func(x, y parser.Span) int { return x.Start.Offset - y.Start.Offset })
+	var b strings.Builder
+	last := 0
+	for _, span := range sorted {
+		b.WriteString(a.Source[last:span.Start.Offset])
+		b.WriteString(name)
+		last = span.End.Offset
+	}
+	b.WriteString(a.Source[last:])
+	source := b.String()
+
+	renamed := Analyse(source)
+	for _, d := range renamed.Diagnostics {
+		if d.Phase == "syntax" || d.Phase == "naming" {
+			return "", nil, fmt.Errorf("renaming %s to %s: %s", old, name, d.Message)
+		}
+	}
+	before, after := a.bindings(), renamed.bindings()
+	for i := range before {
+		if before[i].declaration != after[i].declaration {
+			use := after[i].node.Span.Start
+			return "", nil, fmt.Errorf("renaming %s to %s would change what %s at %d:%d refers to", old, name, after[i].node.Name, use.Line, use.Column)
+		}
+	}
+	return source, spans, nil
+}
+
+// binding is an identifier with the position of its declaration among
+// all identifiers, or -1 if it has none.
+type binding struct {
+	node        *parser.ASTNode
+	declaration int
+}
+
+// bindings lists the identifiers of the program in source order. The
+// lists of two programs that differ only in names line up.
+func (a *Analysis) bindings() []binding {
+	output := make([]binding, 0)
+	index := make(map[int]int)
+	parser.Inspect(a.Root, func(node *parser.ASTNode) bool {
+		if node.Type == VAR || node.Type == NAME {
+			index[int(node.ID)] = len(output)
+			output = append(output, binding{node, -1})
+		}
+		return true
+	})
+	for i, b := range output {
+		if id, ok := a.declarationID(b.node); ok {
+			output[i].declaration = index[id]
+		}
+	}
+	return output
+}
@@ -0,0 +1,63 @@
+package analyser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	input := `glob { g }
+proc {
+	p(a) { local { b } b = a; g = b }
+}
+func {
+	f(n) { local { } n = (n plus g); return n }
+}
+main {
+	var { x }
+	x = f(g);
+	p(x)
+}`
+	tests := []struct {
+		name     string
+		text     string
+		newName  string
+		expected []string
+		err      string
+	}{
+		{"Testing a global", "g }", "total", []string{"glob { total }", "total = b", "(n plus total)", "f(total)"}, ""},
+		{"Testing a parameter", "a)", "arg", []string{"p(arg)", "b = arg", "f(n)", "(n plus g)"}, ""},
+		{"Testing a procedure from a call", "p(x)", "show", []string{"show(a)", "show(x)"}, ""},
+		{"Testing a main variable", "x }", "y", []string{"var { y }", "y = f(g)", "p(y)"}, ""},
+		{"Testing a keyword", "x }", "halt", nil, "halt is a keyword"},
+		{"Testing an invalid name", "x }", "Big", nil, `"Big" is not a valid name`},
+		{"Testing a number", "x }", "12", nil, `"12" is not a valid name`},
+		{"Testing a clash with a local", "a)", "b", nil, "name-rule-violation: conflict of b"},
+		{"Testing a global captured by a local", "g }", "b", nil, "would change what b at 3:28 refers to"},
+		{"Testing a clash between definitions", "f(n)", "p", nil, "name-rule-violation: name redeclaration of p"},
+		{"Testing a non-identifier", "plus", "q", nil, "no variable, procedure or function"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := Analyse(input)
+			source, spans, err := analysis.Rename(nodeAt(t, analysis, tt.text, 0), tt.newName)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, text := range tt.expected {
+				if !strings.Contains(source, text) {
+					t.Errorf("renamed source lacks %q:\n%s", text, source)
+				}
+			}
+			if len(spans) != strings.Count(source, tt.newName) {
+				t.Errorf("got %d spans for %d occurrences of %s", len(spans), strings.Count(source, tt.newName), tt.newName)
+			}
+		})
+	}
+}
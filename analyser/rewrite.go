@@ -0,0 +1,200 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// The rewriting helpers below change an analysed tree while keeping the
+// symbol table in step with it, so the generators keep working on the
+// result.
+
+func ValidateRewriteProgram(root *parser.ASTNode, f func(*parser.ASTNode) *parser.ASTNode) (result *parser.ASTNode, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return RewriteProgram(root, f), nil
+}
+
+// RewriteProgram applies parser.Rewrite to an analysed program, drops the
+// symbol table entries of the nodes that are gone and scopes the nodes f
+// put in. It panics when a remaining node refers to a declaration that was
+// removed or an inserted node breaks the scoping rules, and then leaves the
+// tree and the symbol table as they were.
+func RewriteProgram(root *parser.ASTNode, f func(*parser.ASTNode) *parser.ASTNode) *parser.ASTNode {
+	saved := saveRewrite(root)
+	defer func() {
+		if r := recover(); r != nil {
+			saved.restore()
+			panic(r)
+		}
+	}()
+
+	result := parser.Rewrite(root, f)
+	if rootNode == root {
+		rootNode = result
+	}
+	pruneSymbols(result)
+	scopeInserted(result)
+	return result
+}
+
+func ValidateReplaceSubtree(root, old, replacement *parser.ASTNode) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	ReplaceSubtree(root, old, replacement)
+	return nil
+}
+
+// ReplaceSubtree puts replacement in the place of old below root, like
+// parser.Replace, and updates the symbol table like RewriteProgram. When it
+// panics, the tree and the symbol table are left as they were.
+func ReplaceSubtree(root, old, replacement *parser.ASTNode) {
+	saved := saveRewrite(root)
+	if !parser.Replace(root, old, replacement) {
+		panic(fmt.Sprintf("node %d is not part of the tree", old.ID))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			saved.restore()
+			panic(r)
+		}
+	}()
+	pruneSymbols(root)
+	scopeInserted(root)
+}
+
+// rewriteState is what a rewrite changes: the children of every node, the
+// symbol table, the unique names and the root the recursion check saw.
+type rewriteState struct {
+	children map[*parser.ASTNode][]*parser.ASTNode
+	symbols  SymbolTable
+	names    uniqueNames
+	root     *parser.ASTNode
+}
+
+func saveRewrite(root *parser.ASTNode) rewriteState {
+	children := make(map[*parser.ASTNode][]*parser.ASTNode)
+	parser.Inspect(root, func(node *parser.ASTNode) bool {
+		children[node] = slices.Clone(node.Children)
+		return true
+	})
+	return rewriteState{children, maps.Clone(symbolTable), saveUniqueNames(), rootNode}
+}
+
+func (s rewriteState) restore() {
+	for node, children := range s.children {
+		node.Children = children
+	}
+	symbolTable = s.symbols
+	s.names.restore()
+	rootNode = s.root
+}
+
+// CloneSubtree copies a subtree of an analysed program with fresh IDs.
+// Every copy gets the symbol table entry of its original, except that
+// declarations and scopes inside the subtree refer to their copies and
+// variables declared inside it get fresh unique names, so the copy can be
+// placed next to the original. References to declarations outside the
+// subtree are shared.
+func CloneSubtree(node *parser.ASTNode) *parser.ASTNode {
+	copied, originals := parser.Clone(node)
+	copies := make(map[int]int, len(originals))
+	for copyID, originalID := range originals {
+		copies[int(originalID)] = int(copyID)
+	}
+
+	// Names are handed out in tree order, so a clone gets the same ones on
+	// every run.
+	uniqueIDs := make(map[int]string)
+	parser.Inspect(copied, func(node *parser.ASTNode) bool {
+		info, ok := symbolTable[int(originals[node.ID])]
+		if ok && info.declarationNode == info.nodeID {
+			uniqueIDs[int(node.ID)] = getUniqueVar()
+		}
+		return true
+	})
+
+	for copyID, originalID := range originals {
+		info, ok := symbolTable[int(originalID)]
+		if !ok {
+			continue
+		}
+		info.nodeID = int(copyID)
+		if declaration, ok := copies[info.declarationNode]; ok {
+			info.declarationNode = declaration
+			info.uniqueID = uniqueIDs[declaration]
+		}
+		if scope, ok := copies[info.scopeLevel]; ok {
+			info.scopeLevel = scope
+		}
+		symbolTable[int(copyID)] = info
+	}
+	return copied
+}
+
+// pruneSymbols checks that every node still in the tree still has its
+// declaration and then removes the entries of the nodes that are gone. The
+// symbol table is unchanged when the check fails.
+func pruneSymbols(root *parser.ASTNode) {
+	live := make(map[int]bool)
+	parser.Inspect(root, func(node *parser.ASTNode) bool {
+		live[int(node.ID)] = true
+		return true
+	})
+	for id, info := range symbolTable {
+		if live[id] && !live[info.declarationNode] {
+			panic(fmt.Sprintf("rewrite removed the declaration of %s used by node %d", info.symbolName, id))
+		}
+	}
+	for id := range symbolTable {
+		if !live[id] {
+			delete(symbolTable, id)
+		}
+	}
+}
+
+// scopeInserted runs the scoping pass over a rewritten tree, which checks
+// the nodes put in along with the rest, and gives the inserted nodes the
+// entries it found for them. Nodes that were there before keep theirs; an
+// inserted node shares the unique name of the old declaration it refers to
+// and new declarations get fresh names.
+func scopeInserted(root *parser.ASTNode) {
+	kept, names := symbolTable, saveUniqueNames()
+	AnalyseProgram(root)
+	fresh := symbolTable
+	symbolTable = kept
+	names.restore()
+
+	unique := make(map[string]string)
+	for id, info := range fresh {
+		if old, ok := kept[id]; ok {
+			unique[info.uniqueID] = old.uniqueID
+		}
+	}
+	// Fresh names are handed out in tree order, so a rewrite gets the same
+	// ones on every run.
+	parser.Inspect(root, func(node *parser.ASTNode) bool {
+		info, ok := fresh[int(node.ID)]
+		if _, scoped := kept[int(node.ID)]; !ok || scoped {
+			return true
+		}
+		name, ok := unique[info.uniqueID]
+		if !ok {
+			name = getUniqueVar()
+			unique[info.uniqueID] = name
+		}
+		info.uniqueID = name
+		symbolTable[int(node.ID)] = info
+		return true
+	})
+}
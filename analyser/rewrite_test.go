@@ -0,0 +1,193 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// foldConstants replaces arithmetic on two numbers with its result.
+func foldConstants(node *parser.ASTNode) *parser.ASTNode {
+	if node.Type != TERM || node.Name != "binop" {
+		return node
+	}
+	left, right := node.Children[0], node.Children[2]
+	if left.Name != "atom" || right.Name != "atom" || len(left.Children[0].Children) > 0 || len(right.Children[0].Children) > 0 {
+		return node
+	}
+	a, _ := strconv.Atoi(left.Children[0].Name)
+	b, _ := strconv.Atoi(right.Children[0].Name)
+	switch node.Children[1].Name {
+	case "plus":
+		a += b
+	case "mult":
+		a *= b
+	default:
+		return node
+	}
+	return parser.NewNode(TERM, "atom", parser.NewNode(ATOM, strconv.Itoa(a)))
+}
+
+func TestRewriteProgram(t *testing.T) {
+	root := analyseForTest(t, `glob { }
+proc { }
+func { }
+main {
+	var { x }
+	x = ((2 plus 3) mult 4);
+	print x
+}`)
+	root, err := ValidateRewriteProgram(root, foldConstants)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"aa = 20", "a = aa", "PRINT a"}
+	if program := GenerateProgram(root); !reflect.DeepEqual(program, expected) {
+		t.Errorf("got %v, expected %v", program, expected)
+	}
+
+	// A variable f puts in refers to the declaration in scope.
+	declarations := root.Children[3].Children[0]
+	printed := root.Children[3].Children[1].Children[1].Children[0].Children[0]
+	inserted := parser.NewNode(VAR, "x")
+	root, err = ValidateRewriteProgram(root, func(node *parser.ASTNode) *parser.ASTNode {
+		if node == printed.Children[0] {
+			return inserted
+		}
+		return node
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info := symbolTable[int(inserted.ID)]; info.declarationNode != int(declarations.Children[0].ID) || info.uniqueID != "a" {
+		t.Errorf("got entry %+v for the inserted variable", info)
+	}
+	if program := GenerateProgram(root); !reflect.DeepEqual(program, expected) {
+		t.Errorf("got %v, expected %v", program, expected)
+	}
+
+	tests := []struct {
+		name     string
+		f        func(node *parser.ASTNode) *parser.ASTNode
+		expected string
+	}{
+		{"Testing a removed declaration", func(node *parser.ASTNode) *parser.ASTNode {
+			if node == declarations {
+				return parser.NewNode(VARIABLES, "")
+			}
+			return node
+		}, "removed the declaration of x"},
+		{"Testing an inserted undeclared variable", func(node *parser.ASTNode) *parser.ASTNode {
+			if node == inserted {
+				return parser.NewNode(VAR, "y")
+			}
+			return node
+		}, "undeclared-variable: y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, symbols := parser.FormatAST(root), maps.Clone(symbolTable)
+			_, err := ValidateRewriteProgram(root, tt.f)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("got error %v, expected %q", err, tt.expected)
+			}
+			if parser.FormatAST(root) != tree {
+				t.Errorf("the failed rewrite changed the tree")
+			}
+			if !reflect.DeepEqual(symbolTable, symbols) {
+				t.Errorf("the failed rewrite changed the symbol table")
+			}
+		})
+	}
+}
+
+func TestCloneSubtree(t *testing.T) {
+	root := analyseForTest(t, `glob { g }
+proc {
+	p(a) { local { b } b = a; g = b }
+}
+func { }
+main {
+	var { }
+	p(1)
+}`)
+	def := root.Children[1].Children[0]
+	clone := CloneSubtree(def)
+
+	originalIDs := make(map[int64]bool)
+	parser.Inspect(def, func(node *parser.ASTNode) bool {
+		originalIDs[node.ID] = true
+		return true
+	})
+	parser.Inspect(clone, func(node *parser.ASTNode) bool {
+		if originalIDs[node.ID] {
+			t.Errorf("clone shares ID %d with the original", node.ID)
+		}
+		return true
+	})
+
+	local := def.Children[2].Children[0].Children[0]
+	localCopy := clone.Children[2].Children[0].Children[0]
+	use := clone.Children[2].Children[1].Children[0].Children[0].Children[0]
+	global := clone.Children[2].Children[1].Children[1].Children[0].Children[0]
+	tests := []struct {
+		name     string
+		got      any
+		expected any
+	}{
+		{"Testing the copied declaration", symbolTable[int(localCopy.ID)].declarationNode, int(localCopy.ID)},
+		{"Testing the fresh unique name", symbolTable[int(localCopy.ID)].uniqueID != symbolTable[int(local.ID)].uniqueID, true},
+		{"Testing a reference inside the clone", symbolTable[int(use.ID)].declarationNode, int(localCopy.ID)},
+		{"Testing the unique name of the reference", symbolTable[int(use.ID)].uniqueID, symbolTable[int(localCopy.ID)].uniqueID},
+		{"Testing the scope of the reference", symbolTable[int(use.ID)].scopeLevel, int(clone.ID)},
+		{"Testing a global reference", symbolTable[int(global.ID)].uniqueID, symbolTable[int(root.Children[0].Children[0].ID)].uniqueID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.expected {
+				t.Errorf("got %v, expected %v", tt.got, tt.expected)
+			}
+		})
+	}
+
+	if err := ValidateReplaceSubtree(root, def, clone); err == nil || !strings.Contains(err.Error(), "removed the declaration of p") {
+		t.Errorf("got error %v, expected the call to lose its declaration", err)
+	}
+	if root.Children[1].Children[0] != def {
+		t.Errorf("the rejected replacement stayed in the tree")
+	}
+	if _, ok := symbolTable[int(local.ID)]; !ok {
+		t.Errorf("the rejected replacement removed the entries of the procedure")
+	}
+}
+
+func TestCloneSubtreeNames(t *testing.T) {
+	const program = `glob { }
+proc {
+	p(a b c) { local { d e f } d = a; e = b; f = c }
+}
+func { }
+main {
+	var { }
+	p(1 2 3)
+}`
+	var first []string
+	for i := 0; i < 5; i++ {
+		root := analyseForTest(t, program)
+		names := make([]string, 0)
+		parser.Inspect(CloneSubtree(root.Children[1].Children[0]), func(node *parser.ASTNode) bool {
+			if info, ok := symbolTable[int(node.ID)]; ok {
+				names = append(names, info.uniqueID)
+			}
+			return true
+		})
+		if first == nil {
+			first = names
+		} else if !reflect.DeepEqual(names, first) {
+			t.Fatalf("run %d named the clone %v, the first run %v", i, names, first)
+		}
+	}
+}
@@ -16,6 +16,12 @@ var (
 	FUNCTION_SCOPE  int
 	charSet         string
 	single          bool
+	// visiting is the node the analysis works on, where its errors are
+	// reported.
+	visiting *parser.ASTNode
+	// recycled holds unique names that are free again, handed out before
+	// new ones.
+	recycled []string
 )
 
 func initialiseAnalyser() {
@@ -26,9 +32,15 @@ func initialiseAnalyser() {
 	curIndex = 0
 	charSet = "abcdefghijklmnopqrstuvwxyz"
 	single = true
+	recycled = nil
 }
 
 func getUniqueVar() string {
+	if len(recycled) > 0 {
+		name := recycled[0]
+		recycled = recycled[1:]
+		return name
+	}
 	if single {
 		if varIndex == 25 {
 			single = false
@@ -76,6 +88,7 @@ func visitNode(node *parser.ASTNode) {
 	if node == nil {
 		return
 	}
+	visiting = node
 
 	switch node.Type {
 	case SPL_PROG:
@@ -149,11 +162,9 @@ func handleProgram(node *parser.ASTNode) {
 }
 
 func handleVariables(node *parser.ASTNode) {
-	if len(node.Children) == 0 {
-		return
+	for _, child := range node.Children {
+		declareVar(child)
 	}
-	declareVar(node.Children[0])
-	visitNode(node.Children[1])
 }
 
 func handleProcDefs(node *parser.ASTNode) {
@@ -204,6 +215,7 @@ func handleMaxThree(node *parser.ASTNode) {
 }
 
 func declareVar(node *parser.ASTNode) {
+	visiting = node
 	varname := node.Name
 	nodeID, ok := auxStack.lookup(varname)
 	if ok {
@@ -226,6 +238,7 @@ func declareVar(node *parser.ASTNode) {
 }
 
 func declareName(node *parser.ASTNode) {
+	visiting = node
 	name := node.Name
 	nodeID, ok := auxStack.lookup(name)
 	if ok {
@@ -248,6 +261,7 @@ func declareName(node *parser.ASTNode) {
 }
 
 func handleVar(node *parser.ASTNode) {
+	visiting = node
 	varname := node.Name
 	nodeID, ok := auxStack.lookup(varname)
 	if !ok {
@@ -286,6 +300,7 @@ func handleVar(node *parser.ASTNode) {
 }
 
 func handleName(node *parser.ASTNode) {
+	visiting = node
 	name := node.Name
 	nodeID, ok := auxStack.lookup(name)
 	if !ok {
@@ -0,0 +1,100 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SourceMap relates the intermediate code and the BASIC translated from it
+// back to the SPL source.
+type SourceMap struct {
+	Version      int              `json:"version"`
+	Source       string           `json:"source,omitempty"`
+	Dialect      string           `json:"dialect"`
+	Intermediate []SourceMapEntry `json:"intermediate"`
+	Basic        []SourceMapEntry `json:"basic"`
+}
+
+// SourceMapEntry describes one generated line. Line counts the lines of the
+// generated output from 1 and Number is the BASIC line number, if any.
+// Intermediate is the index of the intermediate instruction a BASIC line
+// was translated from. Node, Instruction and Span locate the SPL
+// instruction that produced the line. Text is a copy of the source line
+// where that instruction starts, without leading or trailing spaces.
+// Lines added by the backend itself, such as the overflow handler, have
+// no origin.
+type SourceMapEntry struct {
+	Line         int          `json:"line"`
+	Number       int          `json:"number,omitempty"`
+	Code         string       `json:"code"`
+	Intermediate *int         `json:"intermediate,omitempty"`
+	Node         int64        `json:"node,omitempty"`
+	Instruction  string       `json:"instruction,omitempty"`
+	Span         *parser.Span `json:"span,omitempty"`
+	Text         string       `json:"text,omitempty"`
+}
+
+func ValidateBasicSourceMap(program []string, dialect *BasicDialect, options BasicOptions, sourceName string) (code []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return BasicSourceMap(program, dialect, options, sourceName), nil
+}
+
+// BasicSourceMap translates the intermediate code like
+// TranslateToBasicDialect and returns the source map of the result as
+// JSON. options.Origins holds the INSTR node of every intermediate
+// instruction, see GenerateIntermediate.
+func BasicSourceMap(program []string, dialect *BasicDialect, options BasicOptions, sourceName string) []byte {
+	lines, numbers, irIndices := translateBasic(program, dialect, options)
+	sourceLines := strings.Split(options.Source, "\n")
+
+	locate := func(entry *SourceMapEntry, index int) {
+		if index < 0 || index >= len(options.Origins) || options.Origins[index] == nil {
+			return
+		}
+		node := options.Origins[index]
+		entry.Node = node.ID
+		entry.Instruction = node.Name
+		if node.Span.IsZero() {
+			return
+		}
+		span := node.Span
+		entry.Span = &span
+		if span.Start.Line <= len(sourceLines) {
+			entry.Text = strings.TrimSpace(sourceLines[span.Start.Line-1])
+		}
+	}
+
+	sourceMap := SourceMap{
+		Version:      1,
+		Source:       sourceName,
+		Dialect:      dialect.Name,
+		Intermediate: make([]SourceMapEntry, len(program)),
+		Basic:        make([]SourceMapEntry, len(lines)),
+	}
+	for i, line := range program {
+		sourceMap.Intermediate[i] = SourceMapEntry{Line: i + 1, Code: line}
+		locate(&sourceMap.Intermediate[i], i)
+	}
+	for i, line := range lines {
+		entry := SourceMapEntry{Line: i + 1, Number: numbers[i], Code: line}
+		if irIndices[i] >= 0 {
+			index := irIndices[i]
+			entry.Intermediate = &index
+			locate(&entry, index)
+		}
+		sourceMap.Basic[i] = entry
+	}
+
+	code, err := json.MarshalIndent(sourceMap, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return append(code, '\n')
+}
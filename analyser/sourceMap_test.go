@@ -0,0 +1,82 @@
+package analyser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBasicSourceMap(t *testing.T) {
+	input := `glob { }
+proc { }
+func { }
+main {
+	var { x }
+	x = 2;
+	while (x > 0) {
+		print x;
+		x = (x minus 1)
+	}
+}`
+	root := analyseForTest(t, input)
+	program, origins := GenerateIntermediate(root)
+	if len(origins) != len(program) {
+		t.Fatalf("got %d origins for %d instructions", len(origins), len(program))
+	}
+	for i, node := range origins {
+		if node == nil || node.Type != INSTR {
+			t.Fatalf("instruction %d (%s) has no INSTR origin", i, program[i])
+		}
+	}
+
+	options := BasicOptions{Numbering: DefaultLineNumbering, Comments: true, Origins: origins, Source: input}
+	var sourceMap SourceMap
+	if err := json.Unmarshal(BasicSourceMap(program, BasicDialects["spl"], options, "loop.txt"), &sourceMap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sourceMap.Source != "loop.txt" || len(sourceMap.Basic) != len(program) {
+		t.Fatalf("unexpected source map header: %+v", sourceMap)
+	}
+
+	tests := []struct {
+		code     string
+		spanLine int
+		column   int
+		text     string
+	}{
+		{"aa = 2", 6, 2, "x = 2;"},
+		{"REM l0", 7, 2, "while (x > 0) {"},
+		{"PRINT a", 8, 3, "print x;"},
+		{"a = ad", 9, 3, "x = (x minus 1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			for _, entry := range sourceMap.Basic {
+				code := strings.SplitN(entry.Code, ": REM", 2)[0]
+				if !strings.HasSuffix(code, " "+tt.code) {
+					continue
+				}
+				if entry.Span == nil || entry.Span.Start.Line != tt.spanLine || entry.Span.Start.Column != tt.column || entry.Text != tt.text {
+					t.Errorf("%s: got span %v and text %q", entry.Code, entry.Span, entry.Text)
+				}
+				if entry.Number != entry.Line*10 || entry.Intermediate == nil {
+					t.Errorf("%s: got number %d and intermediate %v", entry.Code, entry.Number, entry.Intermediate)
+				}
+				return
+			}
+			t.Errorf("no BASIC line ending with %q", tt.code)
+		})
+	}
+
+	comments := 0
+	for _, entry := range sourceMap.Basic {
+		if strings.Contains(entry.Code, ": REM SPL ") {
+			comments++
+		}
+	}
+	// x = 2, the while loop (twice, for its test and its jump back), print
+	// and the assignment in the loop.
+	if comments != 5 {
+		t.Errorf("expected 5 REM comments, got %d", comments)
+	}
+}
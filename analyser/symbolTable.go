@@ -17,9 +17,14 @@ type SemanticInfo struct {
 type SymbolTable map[int]SemanticInfo
 
 func PrettyPrintSymbolTable(st SymbolTable) {
+	fmt.Print(FormatSymbolTable(st))
+}
+
+// FormatSymbolTable renders the symbol table as the text table printed by
+// PrettyPrintSymbolTable.
+func FormatSymbolTable(st SymbolTable) string {
 	if len(st) == 0 {
-		fmt.Println("Symbol table is empty")
-		return
+		return "Symbol table is empty\n"
 	}
 
 	keys := make([]int, 0, len(st))
@@ -63,23 +68,25 @@ func PrettyPrintSymbolTable(st SymbolTable) {
 		strings.Repeat("-", widths.scopeLevel),
 		strings.Repeat("-", widths.declarationNode))
 
-	fmt.Println(separator)
-	fmt.Printf("| %-*s | %-*s | %-*s | %-*s | %-*s |\n",
+	var out strings.Builder
+	fmt.Fprintln(&out, separator)
+	fmt.Fprintf(&out, "| %-*s | %-*s | %-*s | %-*s | %-*s |\n",
 		widths.nodeID, "NodeID",
 		widths.symbolName, "Symbol Name",
 		widths.uniqueID, "Unique ID",
 		widths.scopeLevel, "Scope Level",
 		widths.declarationNode, "Declaration Node")
-	fmt.Println(separator)
+	fmt.Fprintln(&out, separator)
 
 	for _, key := range keys {
 		info := st[key]
-		fmt.Printf("| %-*d | %-*s | %-*s | %-*d | %-*d |\n",
+		fmt.Fprintf(&out, "| %-*d | %-*s | %-*s | %-*d | %-*d |\n",
 			widths.nodeID, info.nodeID,
 			widths.symbolName, info.symbolName,
 			widths.uniqueID, info.uniqueID,
 			widths.scopeLevel, info.scopeLevel,
 			widths.declarationNode, info.declarationNode)
 	}
-	fmt.Println(separator)
+	fmt.Fprintln(&out, separator)
+	return out.String()
 }
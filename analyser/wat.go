@@ -0,0 +1,597 @@
+package analyser
+
+import (
+	"SPL-compiler/parser"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The WebAssembly backend builds a single module description that is
+// rendered either as the text format (TranslateToWat) or as the binary
+// format (TranslateToWasm), so that what is executed in tests is exactly
+// what is shown to the user.
+//
+// The host has to provide three imports in the "spl" namespace:
+//
+//	print_int (param i64)         prints a number followed by a newline
+//	print_str (param i32 i32)     prints len bytes of memory at ptr and a newline
+//	halt                          stops the program
+//
+// The module exports its memory as "memory" and its entry point as "main".
+
+const (
+	wasmPrintInt = 0
+	wasmPrintStr = 1
+	wasmHalt     = 2
+	wasmImports  = 3
+)
+
+type wasmInstr struct {
+	op    string
+	arg   int64
+	label string // symbolic operand shown in the text format
+}
+
+type wasmFunc struct {
+	name    string
+	comment string
+	params  []string
+	locals  []string
+	result  bool
+	export  string
+	body    []wasmInstr
+}
+
+type wasmModule struct {
+	globals        []string
+	globalComments []string
+	funcs          []*wasmFunc
+	data           []byte
+	strings        map[string]int
+}
+
+type wasmGenerator struct {
+	module    *wasmModule
+	globalIdx map[string]int
+	funcIdx   map[string]int
+	localIdx  map[string]int
+	current   *wasmFunc
+}
+
+func ValidateTranslateToWat(root *parser.ASTNode) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return TranslateToWat(root), nil
+}
+
+func ValidateTranslateToWasm(root *parser.ASTNode) (code []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return TranslateToWasm(root), nil
+}
+
+// TranslateToWat emits a WebAssembly text module for an analysed SPL program.
+func TranslateToWat(root *parser.ASTNode) string {
+	return buildWasmModule(root).text()
+}
+
+// TranslateToWasm emits the binary encoding of the module TranslateToWat shows.
+func TranslateToWasm(root *parser.ASTNode) []byte {
+	return buildWasmModule(root).binary()
+}
+
+func buildWasmModule(root *parser.ASTNode) *wasmModule {
+	g := &wasmGenerator{
+		module:    &wasmModule{strings: make(map[string]int)},
+		globalIdx: make(map[string]int),
+		funcIdx:   make(map[string]int),
+	}
+
+	for _, v := range root.Children[0].Children {
+		g.globalIdx[getVar(v)] = len(g.module.globals)
+		g.module.globals = append(g.module.globals, getVar(v))
+		g.module.globalComments = append(g.module.globalComments, v.Name)
+	}
+
+	procs := root.Children[1].Children
+	funcs := root.Children[2].Children
+	for _, def := range append(append([]*parser.ASTNode{}, procs...), funcs...) {
+		name := symbolTable[int(def.Children[0].ID)].uniqueID
+		g.funcIdx[name] = wasmImports + len(g.module.funcs)
+		f := &wasmFunc{name: name, result: def.Type == FDEF}
+		if def.Type == FDEF {
+			f.comment = "func " + def.Children[0].Name
+		} else {
+			f.comment = "proc " + def.Children[0].Name
+		}
+		for _, param := range parameterList(def) {
+			f.params = append(f.params, getVar(param))
+		}
+		for _, local := range localList(def) {
+			f.locals = append(f.locals, getVar(local))
+		}
+		g.module.funcs = append(g.module.funcs, f)
+	}
+
+	for i, def := range append(append([]*parser.ASTNode{}, procs...), funcs...) {
+		g.enter(g.module.funcs[i])
+		g.algo(def.Children[2].Children[1])
+		if def.Type == FDEF {
+			g.atom(def.Children[3])
+		}
+	}
+
+	mainProg := root.Children[3]
+	mainFunc := &wasmFunc{name: "main", export: "main", comment: "main"}
+	for _, v := range mainProg.Children[0].Children {
+		mainFunc.locals = append(mainFunc.locals, getVar(v))
+	}
+	g.module.funcs = append(g.module.funcs, mainFunc)
+	g.enter(mainFunc)
+	g.algo(mainProg.Children[1])
+
+	return g.module
+}
+
+func (g *wasmGenerator) enter(f *wasmFunc) {
+	g.current = f
+	g.localIdx = make(map[string]int)
+	for _, name := range append(append([]string{}, f.params...), f.locals...) {
+		g.localIdx[name] = len(g.localIdx)
+	}
+}
+
+func (g *wasmGenerator) emit(op string, arg int64, label string) {
+	g.current.body = append(g.current.body, wasmInstr{op: op, arg: arg, label: label})
+}
+
+func (g *wasmGenerator) algo(node *parser.ASTNode) {
+	for _, instr := range node.Children {
+		g.instr(instr)
+	}
+}
+
+func (g *wasmGenerator) instr(node *parser.ASTNode) {
+	switch node.Name {
+	case "halt":
+		g.emit("call", wasmHalt, "$halt")
+		g.emit("unreachable", 0, "")
+	case "print":
+		output := node.Children[0]
+		if len(output.Children) > 0 {
+			g.atom(output.Children[0])
+			g.emit("call", wasmPrintInt, "$print_int")
+			return
+		}
+		offset := g.stringOffset(output.Name)
+		g.emit("i32.const", int64(offset), "")
+		g.emit("i32.const", int64(len(output.Name)), "")
+		g.emit("call", wasmPrintStr, "$print_str")
+	case "call":
+		g.call(node.Children[0], node.Children[1])
+	case "assign":
+		assign := node.Children[0]
+		if assign.Name == "call" {
+			g.call(assign.Children[1], assign.Children[2])
+		} else {
+			g.term(assign.Children[1])
+		}
+		g.store(getVar(assign.Children[0]))
+	case "loop":
+		g.loop(node.Children[0])
+	case "branch":
+		g.branch(node.Children[0])
+	default:
+		panic("expected 'halt', 'print', 'call', 'assign', 'loop' or 'branch' Instr node name")
+	}
+}
+
+func (g *wasmGenerator) call(name, input *parser.ASTNode) {
+	for _, atom := range input.Children {
+		g.atom(atom)
+	}
+	callee := declaredUniqueID(name)
+	g.emit("call", int64(g.funcIdx[callee]), "$"+callee)
+}
+
+func (g *wasmGenerator) loop(node *parser.ASTNode) {
+	switch node.Name {
+	case "while":
+		g.emit("block", 0, "")
+		g.emit("loop", 0, "")
+		g.term(node.Children[0])
+		g.emit("i32.eqz", 0, "")
+		g.emit("br_if", 1, "")
+		g.algo(node.Children[1])
+		g.emit("br", 0, "")
+		g.emit("end", 0, "")
+		g.emit("end", 0, "")
+	case "do":
+		g.emit("loop", 0, "")
+		g.algo(node.Children[0])
+		g.term(node.Children[1])
+		g.emit("i32.eqz", 0, "")
+		g.emit("br_if", 0, "")
+		g.emit("end", 0, "")
+	default:
+		panic("expected 'while' or 'do' Loop node name")
+	}
+}
+
+func (g *wasmGenerator) branch(node *parser.ASTNode) {
+	g.term(node.Children[0])
+	g.emit("if", 0, "")
+	g.algo(node.Children[1])
+	switch node.Name {
+	case "if":
+	case "ifelse":
+		g.emit("else", 0, "")
+		g.algo(node.Children[2])
+	default:
+		panic("expected 'if' or 'ifelse' Branch node name")
+	}
+	g.emit("end", 0, "")
+}
+
+// term leaves an i64 for numeric terms and an i32 for boolean terms on the stack.
+func (g *wasmGenerator) term(node *parser.ASTNode) {
+	switch node.Name {
+	case "atom":
+		g.atom(node.Children[0])
+	case "unop":
+		switch node.Children[0].Name {
+		case "neg":
+			g.emit("i64.const", 0, "")
+			g.term(node.Children[1])
+			g.emit("i64.sub", 0, "")
+		case "not":
+			g.term(node.Children[1])
+			g.emit("i32.eqz", 0, "")
+		default:
+			panic("expected 'neg' or 'not' UnOp node name")
+		}
+	case "binop":
+		g.term(node.Children[0])
+		g.term(node.Children[2])
+		g.emit(wasmBinOp(node.Children[1]), 0, "")
+	default:
+		panic("expected 'atom', 'unop', or 'binop' Term node name")
+	}
+}
+
+func wasmBinOp(node *parser.ASTNode) string {
+	switch node.Name {
+	case "eq":
+		return "i64.eq"
+	case ">":
+		return "i64.gt_s"
+	case "or":
+		return "i32.or"
+	case "and":
+		return "i32.and"
+	case "plus":
+		return "i64.add"
+	case "minus":
+		return "i64.sub"
+	case "mult":
+		return "i64.mul"
+	case "div":
+		return "i64.div_s"
+	default:
+		panic("expected 'eq', '>', 'or', 'and', 'plus', 'minus', 'mult', or 'div' BinOp node name")
+	}
+}
+
+func (g *wasmGenerator) atom(node *parser.ASTNode) {
+	if len(node.Children) > 0 {
+		g.load(getVar(node.Children[0]))
+		return
+	}
+	g.emit("i64.const", numberLiteral(node.Name), "")
+}
+
+func (g *wasmGenerator) load(name string) {
+	if idx, ok := g.localIdx[name]; ok {
+		g.emit("local.get", int64(idx), "$"+name)
+		return
+	}
+	g.emit("global.get", int64(g.globalIdx[name]), "$"+name)
+}
+
+func (g *wasmGenerator) store(name string) {
+	if idx, ok := g.localIdx[name]; ok {
+		g.emit("local.set", int64(idx), "$"+name)
+		return
+	}
+	g.emit("global.set", int64(g.globalIdx[name]), "$"+name)
+}
+
+func (g *wasmGenerator) stringOffset(s string) int {
+	if offset, ok := g.module.strings[s]; ok {
+		return offset
+	}
+	offset := len(g.module.data)
+	g.module.strings[s] = offset
+	g.module.data = append(g.module.data, s...)
+	return offset
+}
+
+func (m *wasmModule) pages() int {
+	return len(m.data)/65536 + 1
+}
+
+func (m *wasmModule) text() string {
+	output := []string{
+		"(module",
+		`  (import "spl" "print_int" (func $print_int (param i64)))`,
+		`  (import "spl" "print_str" (func $print_str (param i32 i32)))`,
+		`  (import "spl" "halt" (func $halt))`,
+		fmt.Sprintf(`  (memory (export "memory") %d)`, m.pages()),
+	}
+	if len(m.data) > 0 {
+		output = append(output, fmt.Sprintf(`  (data (i32.const 0) "%s")`, watString(m.data)))
+	}
+	for i, name := range m.globals {
+		output = append(output, fmt.Sprintf("  (global $%s (mut i64) (i64.const 0)) ;; %s", name, m.globalComments[i]))
+	}
+
+	for _, f := range m.funcs {
+		header := "  (func $" + f.name
+		if f.export != "" {
+			header += fmt.Sprintf(` (export "%s")`, f.export)
+		}
+		for _, param := range f.params {
+			header += fmt.Sprintf(" (param $%s i64)", param)
+		}
+		if f.result {
+			header += " (result i64)"
+		}
+		output = append(output, header+" ;; "+f.comment)
+		for _, local := range f.locals {
+			output = append(output, fmt.Sprintf("    (local $%s i64)", local))
+		}
+		depth := 2
+		for _, instr := range f.body {
+			if instr.op == "end" || instr.op == "else" {
+				depth--
+			}
+			line := strings.Repeat("  ", depth) + instr.op
+			switch instr.op {
+			case "call", "local.get", "local.set", "global.get", "global.set":
+				line += " " + instr.label
+			case "br", "br_if", "i32.const", "i64.const":
+				line += fmt.Sprint(" ", instr.arg)
+			}
+			output = append(output, line)
+			if instr.op == "block" || instr.op == "loop" || instr.op == "if" || instr.op == "else" {
+				depth++
+			}
+		}
+		output = append(output, "  )")
+	}
+	output = append(output, ")", "")
+	return strings.Join(output, "\n")
+}
+
+func watString(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		if c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "\\%02x", c)
+		}
+	}
+	return b.String()
+}
+
+var wasmOpcodes = map[string]byte{
+	"unreachable": 0x00,
+	"block":       0x02,
+	"loop":        0x03,
+	"if":          0x04,
+	"else":        0x05,
+	"end":         0x0b,
+	"br":          0x0c,
+	"br_if":       0x0d,
+	"call":        0x10,
+	"local.get":   0x20,
+	"local.set":   0x21,
+	"global.get":  0x23,
+	"global.set":  0x24,
+	"i32.const":   0x41,
+	"i64.const":   0x42,
+	"i32.eqz":     0x45,
+	"i64.eq":      0x51,
+	"i64.gt_s":    0x55,
+	"i32.and":     0x71,
+	"i32.or":      0x72,
+	"i64.add":     0x7c,
+	"i64.sub":     0x7d,
+	"i64.mul":     0x7e,
+	"i64.div_s":   0x7f,
+}
+
+const (
+	wasmI32       = 0x7f
+	wasmI64       = 0x7e
+	wasmEmptyType = 0x40
+)
+
+func (m *wasmModule) binary() []byte {
+	var out bytes.Buffer
+	out.Write([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00})
+
+	// Function types are deduplicated by their encoding.
+	types := make([][]byte, 0)
+	typeIndex := func(params []byte, result bool) int {
+		var sig bytes.Buffer
+		sig.WriteByte(0x60)
+		writeUleb(&sig, uint64(len(params)))
+		sig.Write(params)
+		if result {
+			sig.Write([]byte{1, wasmI64})
+		} else {
+			sig.WriteByte(0)
+		}
+		for i, t := range types {
+			if bytes.Equal(t, sig.Bytes()) {
+				return i
+			}
+		}
+		types = append(types, sig.Bytes())
+		return len(types) - 1
+	}
+	importTypes := []int{
+		typeIndex([]byte{wasmI64}, false),
+		typeIndex([]byte{wasmI32, wasmI32}, false),
+		typeIndex(nil, false),
+	}
+	funcTypes := make([]int, len(m.funcs))
+	for i, f := range m.funcs {
+		funcTypes[i] = typeIndex(bytes.Repeat([]byte{wasmI64}, len(f.params)), f.result)
+	}
+
+	var section bytes.Buffer
+	writeUleb(&section, uint64(len(types)))
+	for _, t := range types {
+		section.Write(t)
+	}
+	writeSection(&out, 1, section.Bytes())
+
+	section.Reset()
+	writeUleb(&section, uint64(len(importTypes)))
+	for i, name := range []string{"print_int", "print_str", "halt"} {
+		writeName(&section, "spl")
+		writeName(&section, name)
+		section.WriteByte(0x00)
+		writeUleb(&section, uint64(importTypes[i]))
+	}
+	writeSection(&out, 2, section.Bytes())
+
+	section.Reset()
+	writeUleb(&section, uint64(len(funcTypes)))
+	for _, t := range funcTypes {
+		writeUleb(&section, uint64(t))
+	}
+	writeSection(&out, 3, section.Bytes())
+
+	section.Reset()
+	section.Write([]byte{1, 0x00})
+	writeUleb(&section, uint64(m.pages()))
+	writeSection(&out, 5, section.Bytes())
+
+	section.Reset()
+	writeUleb(&section, uint64(len(m.globals)))
+	for range m.globals {
+		section.Write([]byte{wasmI64, 0x01, 0x42, 0x00, 0x0b})
+	}
+	writeSection(&out, 6, section.Bytes())
+
+	section.Reset()
+	exports := 1
+	for _, f := range m.funcs {
+		if f.export != "" {
+			exports++
+		}
+	}
+	writeUleb(&section, uint64(exports))
+	writeName(&section, "memory")
+	section.Write([]byte{0x02, 0x00})
+	for i, f := range m.funcs {
+		if f.export != "" {
+			writeName(&section, f.export)
+			section.WriteByte(0x00)
+			writeUleb(&section, uint64(wasmImports+i))
+		}
+	}
+	writeSection(&out, 7, section.Bytes())
+
+	section.Reset()
+	writeUleb(&section, uint64(len(m.funcs)))
+	for _, f := range m.funcs {
+		var body bytes.Buffer
+		if len(f.locals) > 0 {
+			body.WriteByte(1)
+			writeUleb(&body, uint64(len(f.locals)))
+			body.WriteByte(wasmI64)
+		} else {
+			body.WriteByte(0)
+		}
+		for _, instr := range f.body {
+			opcode, ok := wasmOpcodes[instr.op]
+			if !ok {
+				panic("unknown wasm instruction: " + instr.op)
+			}
+			body.WriteByte(opcode)
+			switch instr.op {
+			case "block", "loop", "if":
+				body.WriteByte(wasmEmptyType)
+			case "br", "br_if", "call", "local.get", "local.set", "global.get", "global.set":
+				writeUleb(&body, uint64(instr.arg))
+			case "i32.const", "i64.const":
+				writeSleb(&body, instr.arg)
+			}
+		}
+		body.WriteByte(0x0b)
+		writeUleb(&section, uint64(body.Len()))
+		section.Write(body.Bytes())
+	}
+	writeSection(&out, 10, section.Bytes())
+
+	if len(m.data) > 0 {
+		section.Reset()
+		section.Write([]byte{1, 0x00, 0x41, 0x00, 0x0b})
+		writeUleb(&section, uint64(len(m.data)))
+		section.Write(m.data)
+		writeSection(&out, 11, section.Bytes())
+	}
+
+	return out.Bytes()
+}
+
+func writeSection(out *bytes.Buffer, id byte, content []byte) {
+	out.WriteByte(id)
+	writeUleb(out, uint64(len(content)))
+	out.Write(content)
+}
+
+func writeName(out *bytes.Buffer, name string) {
+	writeUleb(out, uint64(len(name)))
+	out.WriteString(name)
+}
+
+func writeUleb(out *bytes.Buffer, v uint64) {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			b |= 0x80
+		}
+		out.WriteByte(b)
+		if v == 0 {
+			return
+		}
+	}
+}
+
+func writeSleb(out *bytes.Buffer, v int64) {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			out.WriteByte(b)
+			return
+		}
+		out.WriteByte(b | 0x80)
+	}
+}
@@ -0,0 +1,37 @@
+package analyser
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"SPL-compiler/wasmrun"
+)
+
+func TestTranslateToWasm(t *testing.T) {
+	for _, tt := range backendPrograms {
+		t.Run(tt.name, func(t *testing.T) {
+			root := analyseForTest(t, tt.input)
+			text, err := ValidateTranslateToWat(root)
+			if err != nil {
+				t.Fatalf("translate: %v", err)
+			}
+			if !strings.HasPrefix(text, "(module") {
+				t.Errorf("expected a text module, got\n%s", text)
+			}
+			wasm, err := ValidateTranslateToWasm(root)
+			if err != nil {
+				t.Fatalf("translate: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := wasmrun.Run(context.Background(), wasm, &out); err != nil {
+				t.Fatalf("run: %v\n%s", err, text)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("expected output %q, got %q\n%s", tt.expected, out.String(), text)
+			}
+		})
+	}
+}
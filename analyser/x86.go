@@ -0,0 +1,206 @@
+package analyser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func ValidateTranslateToX86(program []string) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return TranslateToX86(program), nil
+}
+
+// TranslateToX86 translates the intermediate code into GNU assembler x86-64
+// for Linux. The output is self-contained: it defines _start, a tiny runtime
+// for printing and exiting through system calls, and every IR variable as a
+// quad in .data, so it only needs `as` and `ld` to become an executable.
+// Unlike TranslateToBasic the program slice is left untouched.
+func TranslateToX86(program []string) string {
+	variables := make(map[string]bool)
+	strs := make([]string, 0)
+	text := make([]string, 0)
+
+	operand := func(value, reg string) string {
+		if isNumber(value) {
+			return fmt.Sprintf("\tmovabsq $%s, %s", x86Number(value), reg)
+		}
+		variables[value] = true
+		return fmt.Sprintf("\tmovq %s(%%rip), %s", x86Var(value), reg)
+	}
+
+	for _, line := range program {
+		text = append(text, "\t# "+line)
+		switch {
+		case line == "STOP":
+			text = append(text, "\tcall spl_halt")
+		case strings.HasPrefix(line, `PRINT "`):
+			str := strings.TrimSuffix(strings.TrimPrefix(line, `PRINT "`), `"`)
+			label := fmt.Sprintf("spl_str%d", len(strs))
+			strs = append(strs, fmt.Sprintf("%s:\n\t.ascii \"%s\"", label, x86String(str)))
+			text = append(text,
+				fmt.Sprintf("\tleaq %s(%%rip), %%rdi", label),
+				fmt.Sprintf("\tmovq $%d, %%rsi", len(str)),
+				"\tcall spl_print_str")
+		case strings.HasPrefix(line, "PRINT "):
+			text = append(text,
+				operand(strings.TrimPrefix(line, "PRINT "), "%rdi"),
+				"\tcall spl_print_int")
+		case strings.HasPrefix(line, "REM "):
+			text = append(text, x86Label(strings.TrimPrefix(line, "REM "))+":")
+		case strings.HasPrefix(line, "GOTO "):
+			text = append(text, "\tjmp "+x86Label(strings.TrimPrefix(line, "GOTO ")))
+		case strings.HasPrefix(line, "IF "):
+			tokens := strings.Fields(line)
+			if len(tokens) != 6 || tokens[4] != "THEN" {
+				panic("malformed conditional jump: " + line)
+			}
+			jump := map[string]string{"=": "je", ">": "jg"}[tokens[2]]
+			if jump == "" {
+				panic("unsupported comparison in: " + line)
+			}
+			text = append(text,
+				operand(tokens[1], "%rax"),
+				operand(tokens[3], "%rcx"),
+				"\tcmpq %rcx, %rax",
+				fmt.Sprintf("\t%s %s", jump, x86Label(tokens[5])))
+		default:
+			tokens := strings.Fields(line)
+			if len(tokens) < 3 || tokens[1] != "=" {
+				panic("unsupported intermediate instruction: " + line)
+			}
+			target := tokens[0]
+			variables[target] = true
+			switch len(tokens) {
+			case 3:
+				if strings.HasPrefix(tokens[2], "-") {
+					text = append(text, operand(strings.TrimPrefix(tokens[2], "-"), "%rax"), "\tnegq %rax")
+				} else {
+					text = append(text, operand(tokens[2], "%rax"))
+				}
+			case 5:
+				text = append(text, operand(tokens[2], "%rax"), operand(tokens[4], "%rcx"))
+				switch tokens[3] {
+				case "+":
+					text = append(text, "\taddq %rcx, %rax")
+				case "-":
+					text = append(text, "\tsubq %rcx, %rax")
+				case "*":
+					text = append(text, "\timulq %rcx, %rax")
+				case "/":
+					text = append(text, "\tcqto", "\tidivq %rcx")
+				default:
+					panic("unsupported operator in: " + line)
+				}
+			default:
+				panic("unsupported intermediate instruction: " + line)
+			}
+			text = append(text, fmt.Sprintf("\tmovq %%rax, %s(%%rip)", x86Var(target)))
+		}
+	}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	output := []string{"\t.data", "\t.balign 8"}
+	for _, name := range names {
+		output = append(output, fmt.Sprintf("%s:\n\t.quad 0", x86Var(name)))
+	}
+	output = append(output, strs...)
+	output = append(output, "", "\t.text", "\t.globl _start", "_start:")
+	output = append(output, text...)
+	output = append(output, "\tcall spl_halt", "")
+	output = append(output, x86Runtime)
+	return strings.Join(output, "\n")
+}
+
+// x86Runtime prints signed decimal numbers and strings followed by a
+// newline with write(2) and stops the program with exit(2).
+const x86Runtime = `# void spl_print_int(long value /* %rdi */)
+spl_print_int:
+	subq $32, %rsp
+	movq %rdi, %rax
+	leaq 32(%rsp), %rsi
+	decq %rsi
+	movb $10, (%rsi)
+	movq $10, %rcx
+	testq %rax, %rax
+	jns 1f
+	negq %rax
+1:
+	xorq %rdx, %rdx
+	divq %rcx
+	addb $48, %dl
+	decq %rsi
+	movb %dl, (%rsi)
+	testq %rax, %rax
+	jnz 1b
+	testq %rdi, %rdi
+	jns 2f
+	decq %rsi
+	movb $45, (%rsi)
+2:
+	leaq 32(%rsp), %rdx
+	subq %rsi, %rdx
+	movq $1, %rax
+	movq $1, %rdi
+	syscall
+	addq $32, %rsp
+	ret
+
+# void spl_print_str(const char *text /* %rdi */, long length /* %rsi */)
+spl_print_str:
+	movq %rsi, %rdx
+	movq %rdi, %rsi
+	movq $1, %rax
+	movq $1, %rdi
+	syscall
+	leaq spl_newline(%rip), %rsi
+	movq $1, %rdx
+	movq $1, %rax
+	movq $1, %rdi
+	syscall
+	ret
+
+# void spl_halt(void)
+spl_halt:
+	movq $60, %rax
+	xorq %rdi, %rdi
+	syscall
+
+	.data
+spl_newline:
+	.byte 10
+`
+
+func x86Number(literal string) string {
+	return fmt.Sprint(numberLiteral(literal))
+}
+
+func x86Var(name string) string {
+	return "spl_v_" + name
+}
+
+func x86Label(label string) string {
+	return ".L" + label
+}
+
+func x86String(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "\\%03o", c)
+		}
+	}
+	return b.String()
+}
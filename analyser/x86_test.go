@@ -0,0 +1,54 @@
+package analyser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestTranslateToX86(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("x86-64 Linux executables cannot run here")
+	}
+	as, errAs := exec.LookPath("as")
+	ld, errLd := exec.LookPath("ld")
+	if errAs != nil || errLd != nil {
+		t.Skip("as or ld not available")
+	}
+	for _, tt := range backendPrograms {
+		t.Run(tt.name, func(t *testing.T) {
+			root := analyseForTest(t, tt.input)
+			program, _, err := ValidateCodeGeneration(root)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			code, err := ValidateTranslateToX86(program)
+			if err != nil {
+				t.Fatalf("translate: %v", err)
+			}
+
+			dir := t.TempDir()
+			source := filepath.Join(dir, "prog.s")
+			object := filepath.Join(dir, "prog.o")
+			binary := filepath.Join(dir, "prog")
+			if err := os.WriteFile(source, []byte(code), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if out, err := exec.Command(as, "-o", object, source).CombinedOutput(); err != nil {
+				t.Fatalf("as: %v\n%s\n%s", err, out, code)
+			}
+			if out, err := exec.Command(ld, "-o", binary, object).CombinedOutput(); err != nil {
+				t.Fatalf("ld: %v\n%s", err, out)
+			}
+			out, err := exec.Command(binary).Output()
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if string(out) != tt.expected {
+				t.Errorf("expected output %q, got %q\n%s", tt.expected, out, code)
+			}
+		})
+	}
+}
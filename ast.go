@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"SPL-compiler/parser"
+)
+
+// ast implements `spl ast [--json] file.spl`, which parses a program and
+// writes its tree, as indented text or in the JSON schema of
+// parser.MarshalAST, to stdout or the -o file.
+func ast(args []string) {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "write the tree as JSON")
+	output := flags.String("o", "", "output file, stdout when empty")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: spl ast [--json] [-o file] file.spl")
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+	root, err := parser.Validate(string(content))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Syntax error:", err)
+		os.Exit(1)
+	}
+
+	var code string
+	if *asJSON {
+		data, err := parser.MarshalAST(root)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Encoding error:", err)
+			os.Exit(1)
+		}
+		code = string(data) + "\n"
+	} else {
+		code = parser.FormatAST(root)
+	}
+	if *output == "" {
+		fmt.Print(code)
+	} else {
+		writeToFile(*output, code)
+	}
+}
@@ -0,0 +1,93 @@
+// Package backend defines the interface code generators implement and a
+// registry through which they are selected by name. The built-in targets
+// are registered by the analyser package; other packages can add their own
+// by calling Register from an init function.
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"SPL-compiler/parser"
+)
+
+// Program is everything the front end knows about a compiled SPL program.
+// AST has been through scoping, type checking and the recursion check, and
+// Intermediate holds the intermediate code generated for it, with Origins
+// holding the INSTR node each instruction came from. Backends must not
+// modify any of them. Options holds the backend settings given on the
+// command line, such as the BASIC dialect.
+type Program struct {
+	Filename     string
+	Source       string
+	AST          *parser.ASTNode
+	Intermediate []string
+	Origins      []*parser.ASTNode
+	Options      map[string]string
+}
+
+// Option returns the named option, or fallback when it was not given.
+func (p *Program) Option(name, fallback string) string {
+	if value, ok := p.Options[name]; ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+type Backend interface {
+	// Name is the value selecting the backend with --target.
+	Name() string
+	// FileExtension is appended to the output file name, without a dot.
+	FileExtension() string
+	Emit(program *Program) ([]byte, error)
+}
+
+var (
+	mu       sync.RWMutex
+	backends = make(map[string]Backend)
+)
+
+// Register makes a backend available under its name. It panics if the name
+// is empty or already taken.
+func Register(b Backend) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	name := b.Name()
+	if name == "" {
+		panic("backend: Register with an empty name")
+	}
+	if _, dup := backends[name]; dup {
+		panic("backend: Register called twice for " + name)
+	}
+	backends[name] = b
+}
+
+func Lookup(name string) (Backend, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	b, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown target %q (available: %v)", name, namesLocked())
+	}
+	return b, nil
+}
+
+// Names returns the registered backend names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
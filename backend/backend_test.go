@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+type stubBackend struct{ name string }
+
+func (s stubBackend) Name() string          { return s.name }
+func (s stubBackend) FileExtension() string { return "stub" }
+
+func (s stubBackend) Emit(program *Program) ([]byte, error) {
+	return []byte(program.Source), nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register(stubBackend{"test-stub"})
+
+	b, err := Lookup("test-stub")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code, err := b.Emit(&Program{Source: "main { }"})
+	if err != nil || string(code) != "main { }" {
+		t.Errorf("Emit() = %q, %v", code, err)
+	}
+
+	found := false
+	for _, name := range Names() {
+		found = found || name == "test-stub"
+	}
+	if !found {
+		t.Errorf("Names() = %v, missing test-stub", Names())
+	}
+
+	if _, err := Lookup("missing"); err == nil || !strings.Contains(err.Error(), "test-stub") {
+		t.Errorf("expected error listing the available targets, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		backend Backend
+	}{
+		{"Testing duplicate name", stubBackend{"test-stub"}},
+		{"Testing empty name", stubBackend{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected Register to panic")
+				}
+			}()
+			Register(tt.backend)
+		})
+	}
+}
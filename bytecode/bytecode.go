@@ -0,0 +1,269 @@
+// Package bytecode defines a compact stack based instruction set for SPL
+// programs, its serialised .splc file format and a virtual machine that
+// executes it.
+package bytecode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Opcode byte
+
+const (
+	OpHalt        Opcode = iota // stop the program
+	OpConst                     // push Arg
+	OpLoadGlobal                // push globals[Arg]
+	OpStoreGlobal               // pop into globals[Arg]
+	OpLoadLocal                 // push locals[Arg]
+	OpStoreLocal                // pop into locals[Arg]
+	OpAdd                       // pop b, a; push a + b
+	OpSub                       // pop b, a; push a - b
+	OpMul                       // pop b, a; push a * b
+	OpDiv                       // pop b, a; push a / b truncated towards zero
+	OpNeg                       // pop a; push -a
+	OpEq                        // pop b, a; push 1 if a == b else 0
+	OpGt                        // pop b, a; push 1 if a > b else 0
+	OpAnd                       // pop b, a; push 1 if both are non-zero else 0
+	OpOr                        // pop b, a; push 1 if either is non-zero else 0
+	OpNot                       // pop a; push 1 if a is zero else 0
+	OpJump                      // continue at Arg
+	OpJumpIfFalse               // pop a; continue at Arg if a is zero
+	OpCall                      // call Functions[Arg] with its parameters popped from the stack
+	OpReturn                    // return to the caller, passing the top of the stack for functions
+	OpPrint                     // pop a and print it
+	OpPrintString               // print Strings[Arg]
+)
+
+var opcodeNames = [...]string{
+	OpHalt:        "HALT",
+	OpConst:       "CONST",
+	OpLoadGlobal:  "LOADG",
+	OpStoreGlobal: "STOREG",
+	OpLoadLocal:   "LOADL",
+	OpStoreLocal:  "STOREL",
+	OpAdd:         "ADD",
+	OpSub:         "SUB",
+	OpMul:         "MUL",
+	OpDiv:         "DIV",
+	OpNeg:         "NEG",
+	OpEq:          "EQ",
+	OpGt:          "GT",
+	OpAnd:         "AND",
+	OpOr:          "OR",
+	OpNot:         "NOT",
+	OpJump:        "JUMP",
+	OpJumpIfFalse: "JUMPF",
+	OpCall:        "CALL",
+	OpReturn:      "RET",
+	OpPrint:       "PRINT",
+	OpPrintString: "PRINTS",
+}
+
+func (op Opcode) String() string {
+	if int(op) < len(opcodeNames) {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("OP(%d)", byte(op))
+}
+
+// HasArg reports whether the opcode carries an operand.
+func (op Opcode) HasArg() bool {
+	switch op {
+	case OpConst, OpLoadGlobal, OpStoreGlobal, OpLoadLocal, OpStoreLocal,
+		OpJump, OpJumpIfFalse, OpCall, OpPrintString:
+		return true
+	}
+	return false
+}
+
+type Instruction struct {
+	Op  Opcode
+	Arg int64
+}
+
+// Function is a procedure, a function or the main program. Parameters
+// occupy the first local slots.
+type Function struct {
+	Name    string
+	Params  int
+	Locals  int
+	Returns bool
+	Code    []Instruction
+}
+
+type Program struct {
+	Globals   int
+	Strings   []string
+	Functions []Function
+	Entry     int
+}
+
+// Disassemble renders a program in a human readable listing.
+func (p *Program) Disassemble() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "globals %d\n", p.Globals)
+	for i, s := range p.Strings {
+		fmt.Fprintf(&b, "string %d %q\n", i, s)
+	}
+	for i, f := range p.Functions {
+		entry := ""
+		if i == p.Entry {
+			entry = " entry"
+		}
+		fmt.Fprintf(&b, "function %d %s params=%d locals=%d returns=%t%s\n",
+			i, f.Name, f.Params, f.Locals, f.Returns, entry)
+		for pc, instr := range f.Code {
+			if instr.Op.HasArg() {
+				fmt.Fprintf(&b, "  %4d %-6s %d\n", pc, instr.Op, instr.Arg)
+			} else {
+				fmt.Fprintf(&b, "  %4d %s\n", pc, instr.Op)
+			}
+		}
+	}
+	return b.String()
+}
+
+// The .splc format is the magic "SPLC", a version byte and then, as
+// varints, the number of globals, the entry function, the string table and
+// the functions. Each instruction is its opcode byte followed by a varint
+// operand when the opcode has one.
+var magic = []byte("SPLC")
+
+const version = 1
+
+// Encode serialises a program into the .splc format.
+func Encode(p *Program) []byte {
+	var out bytes.Buffer
+	out.Write(magic)
+	out.WriteByte(version)
+	writeUvarint(&out, uint64(p.Globals))
+	writeUvarint(&out, uint64(p.Entry))
+	writeUvarint(&out, uint64(len(p.Strings)))
+	for _, s := range p.Strings {
+		writeString(&out, s)
+	}
+	writeUvarint(&out, uint64(len(p.Functions)))
+	for _, f := range p.Functions {
+		writeString(&out, f.Name)
+		writeUvarint(&out, uint64(f.Params))
+		writeUvarint(&out, uint64(f.Locals))
+		if f.Returns {
+			out.WriteByte(1)
+		} else {
+			out.WriteByte(0)
+		}
+		writeUvarint(&out, uint64(len(f.Code)))
+		for _, instr := range f.Code {
+			out.WriteByte(byte(instr.Op))
+			if instr.Op.HasArg() {
+				writeVarint(&out, instr.Arg)
+			}
+		}
+	}
+	return out.Bytes()
+}
+
+// Decode reads a program in the .splc format.
+func Decode(data []byte) (p *Program, err error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, errors.New("not a .splc file")
+	}
+	r := bufio.NewReader(bytes.NewReader(data[len(magic):]))
+	defer func() {
+		if r := recover(); r != nil {
+			p, err = nil, fmt.Errorf("corrupt .splc file: %v", r)
+		}
+	}()
+
+	if v := readByte(r); v != version {
+		return nil, fmt.Errorf("unsupported .splc version %d", v)
+	}
+	// Every counted element takes at least one byte, which bounds the
+	// allocations a corrupt file can cause.
+	limit := len(data)
+	p = &Program{}
+	p.Globals = readCount(r, limit)
+	p.Entry = int(readUvarint(r))
+	p.Strings = make([]string, readCount(r, limit))
+	for i := range p.Strings {
+		p.Strings[i] = readString(r, limit)
+	}
+	p.Functions = make([]Function, readCount(r, limit))
+	for i := range p.Functions {
+		f := &p.Functions[i]
+		f.Name = readString(r, limit)
+		f.Params = readCount(r, limit)
+		f.Locals = readCount(r, limit)
+		f.Returns = readByte(r) == 1
+		f.Code = make([]Instruction, readCount(r, limit))
+		for pc := range f.Code {
+			f.Code[pc].Op = Opcode(readByte(r))
+			if f.Code[pc].Op.HasArg() {
+				f.Code[pc].Arg = readVarint(r)
+			}
+		}
+	}
+	if p.Entry >= len(p.Functions) {
+		return nil, fmt.Errorf("entry function %d does not exist", p.Entry)
+	}
+	return p, nil
+}
+
+func writeUvarint(out *bytes.Buffer, v uint64) {
+	out.Write(binary.AppendUvarint(nil, v))
+}
+
+func writeVarint(out *bytes.Buffer, v int64) {
+	out.Write(binary.AppendVarint(nil, v))
+}
+
+func writeString(out *bytes.Buffer, s string) {
+	writeUvarint(out, uint64(len(s)))
+	out.WriteString(s)
+}
+
+func readByte(r *bufio.Reader) byte {
+	b, err := r.ReadByte()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func readUvarint(r *bufio.Reader) uint64 {
+	v, err := binary.ReadUvarint(r)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func readVarint(r *bufio.Reader) int64 {
+	v, err := binary.ReadVarint(r)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func readCount(r *bufio.Reader, limit int) int {
+	v := readUvarint(r)
+	if v > uint64(limit) {
+		panic(fmt.Sprintf("count %d exceeds file size", v))
+	}
+	return int(v)
+}
+
+func readString(r *bufio.Reader, limit int) string {
+	buf := make([]byte, readCount(r, limit))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		panic(err)
+	}
+	return string(buf)
+}
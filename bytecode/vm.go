@@ -0,0 +1,152 @@
+package bytecode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrStepLimit is returned when a program executes more instructions than
+// the VM allows.
+var ErrStepLimit = errors.New("step limit exceeded")
+
+// VM executes programs. Steps counts the instructions executed by the last
+// call to Run; a MaxSteps of zero means no limit.
+type VM struct {
+	Stdout   io.Writer
+	MaxSteps int64
+	Steps    int64
+}
+
+type frame struct {
+	function *Function
+	locals   []int64
+	pc       int
+}
+
+// Run executes the entry function of the program until it returns or halts.
+// Malformed programs (bad indices, stack underflow) result in an error
+// rather than a panic.
+func (vm *VM) Run(p *Program) (err error) {
+	vm.Steps = 0
+	var current *frame
+	defer func() {
+		if r := recover(); r != nil {
+			where := ""
+			if current != nil {
+				where = fmt.Sprintf(" in %s at %d", current.function.Name, current.pc-1)
+			}
+			err = fmt.Errorf("runtime error%s: %v", where, r)
+		}
+	}()
+
+	globals := make([]int64, p.Globals)
+	stack := make([]int64, 0, 64)
+	frames := make([]*frame, 0, 16)
+
+	push := func(v int64) {
+		stack = append(stack, v)
+	}
+	pop := func() int64 {
+		if len(stack) == 0 {
+			panic("stack underflow")
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+	boolean := func(b bool) int64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	call := func(index int64) {
+		f := &p.Functions[index]
+		fr := &frame{function: f, locals: make([]int64, f.Params+f.Locals)}
+		for i := f.Params - 1; i >= 0; i-- {
+			fr.locals[i] = pop()
+		}
+		frames = append(frames, fr)
+		current = fr
+	}
+
+	call(int64(p.Entry))
+	for {
+		if current.pc >= len(current.function.Code) {
+			return fmt.Errorf("runtime error: %s ran past its last instruction", current.function.Name)
+		}
+		if vm.MaxSteps > 0 && vm.Steps >= vm.MaxSteps {
+			return ErrStepLimit
+		}
+		vm.Steps++
+		instr := current.function.Code[current.pc]
+		current.pc++
+
+		switch instr.Op {
+		case OpHalt:
+			return nil
+		case OpConst:
+			push(instr.Arg)
+		case OpLoadGlobal:
+			push(globals[instr.Arg])
+		case OpStoreGlobal:
+			globals[instr.Arg] = pop()
+		case OpLoadLocal:
+			push(current.locals[instr.Arg])
+		case OpStoreLocal:
+			current.locals[instr.Arg] = pop()
+		case OpAdd:
+			b, a := pop(), pop()
+			push(a + b)
+		case OpSub:
+			b, a := pop(), pop()
+			push(a - b)
+		case OpMul:
+			b, a := pop(), pop()
+			push(a * b)
+		case OpDiv:
+			b, a := pop(), pop()
+			if b == 0 {
+				panic("division by zero")
+			}
+			push(a / b)
+		case OpNeg:
+			push(-pop())
+		case OpEq:
+			b, a := pop(), pop()
+			push(boolean(a == b))
+		case OpGt:
+			b, a := pop(), pop()
+			push(boolean(a > b))
+		case OpAnd:
+			b, a := pop(), pop()
+			push(boolean(a != 0 && b != 0))
+		case OpOr:
+			b, a := pop(), pop()
+			push(boolean(a != 0 || b != 0))
+		case OpNot:
+			push(boolean(pop() == 0))
+		case OpJump:
+			current.pc = int(instr.Arg)
+		case OpJumpIfFalse:
+			if pop() == 0 {
+				current.pc = int(instr.Arg)
+			}
+		case OpCall:
+			call(instr.Arg)
+		case OpReturn:
+			frames = frames[:len(frames)-1]
+			if len(frames) == 0 {
+				return nil
+			}
+			current = frames[len(frames)-1]
+		case OpPrint:
+			fmt.Fprintln(vm.Stdout, pop())
+		case OpPrintString:
+			fmt.Fprintln(vm.Stdout, p.Strings[instr.Arg])
+		default:
+			panic(fmt.Sprintf("unknown opcode %d", instr.Op))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package bytecode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestVM(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     []Instruction
+		expected string
+		err      string
+	}{
+		{"Testing arithmetic and printing", []Instruction{
+			{OpConst, -7}, {OpConst, 2}, {OpDiv, 0}, {OpPrint, 0},
+			{OpPrintString, 0}, {OpHalt, 0},
+		}, "-3\nhello\n", ""},
+		{"Testing infinite loop hits the step limit", []Instruction{
+			{OpJump, 0},
+		}, "", ErrStepLimit.Error()},
+		{"Testing division by zero", []Instruction{
+			{OpConst, 1}, {OpConst, 0}, {OpDiv, 0}, {OpHalt, 0},
+		}, "", "division by zero"},
+		{"Testing stack underflow", []Instruction{
+			{OpAdd, 0},
+		}, "", "stack underflow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := &Program{
+				Strings:   []string{"hello"},
+				Functions: []Function{{Name: "main", Code: tt.code}},
+			}
+			var out bytes.Buffer
+			vm := &VM{Stdout: &out, MaxSteps: 1000}
+			err := vm.Run(program)
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("expected output %q, got %q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsCorruptFiles(t *testing.T) {
+	program := &Program{Functions: []Function{{Name: "main", Code: []Instruction{{OpHalt, 0}}}}}
+	data := Encode(program)
+	if _, err := Decode(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < len(data); i++ {
+		if _, err := Decode(data[:i]); err == nil {
+			t.Errorf("expected an error for a file truncated to %d bytes", i)
+		}
+	}
+	if _, err := Decode([]byte("not bytecode")); err == nil {
+		t.Errorf("expected an error for a foreign file, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"SPL-compiler/analyser"
+	"SPL-compiler/parser"
+)
+
+// dot implements `spl dot --ast|--callgraph|--cfg file.spl`, which writes a
+// Graphviz graph of the program to stdout or the -o file.
+func dot(args []string) {
+	flags := flag.NewFlagSet("dot", flag.ExitOnError)
+	ast := flags.Bool("ast", false, "draw the abstract syntax tree")
+	callGraph := flags.Bool("callgraph", false, "draw the call graph between procedures and functions")
+	cfg := flags.Bool("cfg", false, "draw the control-flow graph of every definition's intermediate code")
+	output := flags.String("o", "", "output file, stdout when empty")
+	flags.Parse(args)
+
+	graphs := make([]string, 0)
+	for name, selected := range map[string]bool{"ast": *ast, "callgraph": *callGraph, "cfg": *cfg} {
+		if selected {
+			graphs = append(graphs, name)
+		}
+	}
+	if len(graphs) != 1 || flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: spl dot --ast|--callgraph|--cfg [-o file] file.spl")
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+	root, err := parser.Validate(string(content))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Syntax error:", err)
+		os.Exit(1)
+	}
+	if graphs[0] != "ast" {
+		if err := analyse(root); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	code, err := analyser.ValidateDot(root, graphs[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Graph error:", err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		fmt.Print(code)
+	} else {
+		writeToFile(*output, code)
+	}
+}
+
+// analyse runs the semantic checks on a parsed program without reporting
+// each stage.
+func analyse(root *parser.ASTNode) error {
+	if err := analyser.ValidateScoping(root); err != nil {
+		return fmt.Errorf("Naming error: %v", err)
+	}
+	if err := analyser.ValidateTypeChecking(root); err != nil {
+		return fmt.Errorf("Type error: %v", err)
+	}
+	if err := analyser.ValidateNoRecursion(root); err != nil {
+		return fmt.Errorf("Recursion detected error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"SPL-compiler/lexer"
+	"SPL-compiler/parser"
+)
+
+// format implements `spl fmt [--check | -w] file.spl...`. By default the
+// formatted programs are written to stdout; -w rewrites the files and
+// --check lists the files that are not formatted and exits with status 1
+// if there are any.
+func format(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list unformatted files and fail if there are any")
+	write := flags.Bool("w", false, "write the result back to the files")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || *check && *write {
+		fmt.Fprintln(os.Stderr, "usage: spl fmt [--check | -w] file.spl...")
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	unformatted := false
+	for _, filename := range flags.Args() {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			os.Exit(1)
+		}
+		program, err := parser.ParseProgram(string(content))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: Syntax error: %v\n", filename, err)
+			os.Exit(1)
+		}
+		formatted := parser.FormatProgram(program, lexer.Comments(string(content)))
+
+		switch {
+		case *check:
+			if formatted != string(content) {
+				fmt.Println(filename)
+				unformatted = true
+			}
+		case *write:
+			if formatted != string(content) {
+				writeToFile(filename, formatted)
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+	if unformatted {
+		os.Exit(1)
+	}
+}
@@ -2,4 +2,7 @@ module SPL-compiler
 
 go 1.24.9
 
-require golang.org/x/tools v0.38.0 // indirect
+require (
+	github.com/tetratelabs/wazero v1.9.0
+	golang.org/x/tools v0.38.0 // indirect
+)
@@ -3,16 +3,19 @@ package lexer
 import (
 	"SPL-compiler/token"
 	"fmt"
+	"strings"
 )
 
 // Lexer represents the lexical analyzer
 type Lexer struct {
 	input        string
-	position     int  // current position in input (points to current char)
-	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
-	line         int  // current line number
-	column       int  // current column number
+	position     int       // current position in input (points to current char)
+	readPosition int       // current reading position in input (after current char)
+	ch           byte      // current char under examination
+	line         int       // current line number
+	column       int       // current column number
+	lastLine     int       // line of the previous token, zero before the first
+	comments     []Comment // comments before the token being read
 }
 
 // New creates a new lexer instance
@@ -64,6 +67,65 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+// Comment is a line comment (// up to the end of the line) or a block
+// comment (/* up to */). Text includes the comment markers. A Trailing
+// comment starts on the line of the token before it.
+type Comment struct {
+	Text      string
+	Line      int
+	Column    int
+	Offset    int
+	EndOffset int
+	Trailing  bool
+}
+
+// IsBlock reports whether the comment is a block comment.
+func (c Comment) IsBlock() bool {
+	return len(c.Text) > 1 && c.Text[1] == '*'
+}
+
+// Body returns the text of the comment without its markers and surrounding
+// spaces.
+func (c Comment) Body() string {
+	text := strings.TrimPrefix(c.Text, "//")
+	if c.IsBlock() {
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+	}
+	return strings.TrimSpace(text)
+}
+
+// skipTrivia skips whitespace and returns the comments in between. An
+// unterminated block comment is returned separately.
+func (l *Lexer) skipTrivia() ([]Comment, *Comment) {
+	var comments []Comment
+	for {
+		l.skipWhitespace()
+		if l.ch != '/' || (l.peekChar() != '/' && l.peekChar() != '*') {
+			return comments, nil
+		}
+		comment := Comment{Line: l.line, Column: l.column, Offset: l.position, Trailing: l.line == l.lastLine}
+		if l.peekChar() == '/' {
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		} else {
+			l.readChar()
+			l.readChar()
+			for !(l.ch == '*' && l.peekChar() == '/') {
+				if l.ch == 0 {
+					return comments, &comment
+				}
+				l.readChar()
+			}
+			l.readChar()
+			l.readChar()
+		}
+		comment.EndOffset = min(l.position, len(l.input))
+		comment.Text = l.input[comment.Offset:comment.EndOffset]
+		comments = append(comments, comment)
+	}
+}
+
 // readIdentifier reads an identifier following the pattern [a...z]{a...z}*{0...9}*
 func (l *Lexer) readIdentifier() string {
 	position := l.position
@@ -127,22 +189,33 @@ func (l *Lexer) readString() (string, error) {
 	return result, nil
 }
 
-// Token struct with line and column info for lexer package
+// Token struct with line and column info for lexer package. Line, Column
+// and Offset locate the first character of the token, EndOffset the byte
+// after its last one. Comments holds the comments between the previous
+// token and this one; the parser ignores them.
 type Token struct {
-	Type    token.TokenType
-	Literal string
-	Line    int
-	Column  int
+	Type      token.TokenType
+	Literal   string
+	Line      int
+	Column    int
+	Offset    int
+	EndOffset int
+	Comments  []Comment
 }
 
 // NextToken returns the next token from the input
 func (l *Lexer) NextToken() Token {
 	var tok Token
 
-	l.skipWhitespace()
+	comments, unterminated := l.skipTrivia()
+	l.comments = comments
+	if unterminated != nil {
+		tok.Type = token.ILLEGAL
+		tok.Literal = "unterminated block comment"
+		return l.locate(tok, unterminated.Line, unterminated.Column, unterminated.Offset)
+	}
 
-	tok.Line = l.line
-	tok.Column = l.column
+	line, column, offset := l.line, l.column, l.position
 
 	switch l.ch {
 	case '=':
@@ -164,50 +237,62 @@ func (l *Lexer) NextToken() Token {
 		if err != nil {
 			tok.Type = token.ILLEGAL
 			tok.Literal = err.Error()
-			tok.Line = l.line
-			tok.Column = l.column
 		} else {
 			tok.Type = token.STRING
 			tok.Literal = str
-			tok.Line = l.line
-			tok.Column = l.column
 		}
-		return tok // readString already advances position
+		return l.locate(tok, line, column, offset) // readString already advances position
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
-		tok.Line = l.line
-		tok.Column = l.column
 	default:
 		if isLowercaseLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
-			tok.Line = l.line
-			tok.Column = l.column
 			if tok.Literal == "" {
 				tok.Type = token.ILLEGAL
 			} else {
 				tok.Type = token.LookupIdent(tok.Literal)
 			}
-			return tok // readIdentifier already advances position
+			return l.locate(tok, line, column, offset) // readIdentifier already advances position
 		} else if isDigit(l.ch) {
 			tok.Literal = l.readNumber()
-			tok.Line = l.line
-			tok.Column = l.column
 			if tok.Literal == "" {
 				tok.Type = token.ILLEGAL
 			} else {
 				tok.Type = token.INT
 			}
-			return tok // readNumber already advances position
+			return l.locate(tok, line, column, offset) // readNumber already advances position
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch, l.line, l.column)
 		}
 	}
 
 	l.readChar()
+	return l.locate(tok, line, column, offset)
+}
+
+// locate records where a token starts and that it ends at the current
+// position, which is the first character not read as part of it.
+func (l *Lexer) locate(tok Token, line, column, offset int) Token {
+	tok.Line = line
+	tok.Column = column
+	tok.Offset = offset
+	tok.EndOffset = min(l.position, len(l.input))
+	tok.Comments = l.comments
+	l.comments = nil
+	l.lastLine = line
 	return tok
 }
 
+// Comments returns every comment in the input.
+func Comments(input string) []Comment {
+	comments := make([]Comment, 0)
+	for _, tok := range TokenizeInput(input) {
+		comments = append(comments, tok.Comments...)
+	}
+	return comments
+}
+
 // newToken creates a new token with the given parameters
 func newToken(tokenType token.TokenType, ch byte, line, column int) Token {
 	return Token{
@@ -0,0 +1,63 @@
+package lexer
+
+import (
+	"SPL-compiler/token"
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	input := `// header
+glob { x } /* globals */
+/* two
+   lines */ proc { }
+x = 1 // end`
+	comments := Comments(input)
+	tests := []struct {
+		name     string
+		text     string
+		body     string
+		line     int
+		column   int
+		trailing bool
+	}{
+		{"Testing a line comment", "// header", "header", 1, 1, false},
+		{"Testing a trailing block comment", "/* globals */", "globals", 2, 12, true},
+		{"Testing a block comment over two lines", "/* two\n   lines */", "two\n   lines", 3, 1, false},
+		{"Testing a comment at the end of the input", "// end", "end", 5, 7, true},
+	}
+	if len(comments) != len(tests) {
+		t.Fatalf("got %d comments, expected %d", len(comments), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := comments[i]
+			if c.Text != tt.text || c.Body() != tt.body || c.Line != tt.line || c.Column != tt.column || c.Trailing != tt.trailing {
+				t.Errorf("got %+v with body %q", c, c.Body())
+			}
+			if input[c.Offset:c.EndOffset] != c.Text {
+				t.Errorf("offsets %d-%d do not cover %q", c.Offset, c.EndOffset, c.Text)
+			}
+		})
+	}
+}
+
+func TestCommentTokens(t *testing.T) {
+	tokens := TokenizeInput("glob /* a */ { } // b\n")
+	expected := []token.TokenType{token.GLOB, token.LBRACE, token.RBRACE, token.EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("got %d tokens, expected %d", len(tokens), len(expected))
+	}
+	for i, tok := range tokens {
+		if tok.Type != expected[i] {
+			t.Errorf("token %d: got %s, expected %s", i, tok.Literal, expected[i])
+		}
+	}
+	if len(tokens[1].Comments) != 1 || len(tokens[3].Comments) != 1 {
+		t.Errorf("comments are not attached to the tokens after them")
+	}
+
+	tokens = TokenizeInput("glob /* open")
+	if tok := tokens[1]; tok.Type != token.ILLEGAL || tok.Literal != "unterminated block comment" || tok.Column != 6 {
+		t.Errorf("got %+v, expected an unterminated block comment at column 6", tok)
+	}
+}
@@ -0,0 +1,419 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"SPL-compiler/analyser"
+	"SPL-compiler/lexer"
+	"SPL-compiler/parser"
+)
+
+// Server holds the open documents and their analyses.
+type Server struct {
+	out       io.Writer
+	documents map[string]*document
+	analyses  map[string]*analyser.Incremental
+	shutdown  bool
+}
+
+// errExit ends Serve after an exit notification that followed shutdown.
+var errExit = errors.New("exit")
+
+// Serve answers the messages read from in on out until the client sends
+// exit or in ends. It returns nil after an orderly shutdown.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &Server{
+		out:       out,
+		documents: make(map[string]*document),
+		analyses:  make(map[string]*analyser.Incremental),
+	}
+	reader := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if msg == nil {
+			return err
+		}
+		if err != nil {
+			s.reply(msg, nil, &responseError{parseError, err.Error()})
+			continue
+		}
+		if err := s.handle(msg); err == errExit {
+			if s.shutdown {
+				return nil
+			}
+			return errors.New("exit without shutdown")
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// handler answers a request; its result is sent back unless it fails.
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":                  (*Server).initialize,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/references":     (*Server).references,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/formatting":     (*Server).formatting,
+	"textDocument/prepareRename":  (*Server).prepareRename,
+	"textDocument/rename":         (*Server).rename,
+	"textDocument/completion":     (*Server).completion,
+}
+
+// notifications handle messages that get no response.
+var notifications = map[string]func(s *Server, params json.RawMessage) error{
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+func (s *Server) handle(msg *message) error {
+	if msg.Method == "exit" {
+		return errExit
+	}
+	if msg.ID == nil {
+		if notify, ok := notifications[msg.Method]; ok {
+			return notify(s, msg.Params)
+		}
+		return nil
+	}
+	h, ok := handlers[msg.Method]
+	if !ok {
+		return s.reply(msg, nil, &responseError{methodNotFound, "unsupported method " + msg.Method})
+	}
+	result, err := h(s, msg.Params)
+	if err != nil {
+		return s.reply(msg, nil, &responseError{requestFailed, err.Error()})
+	}
+	return s.reply(msg, result, nil)
+}
+
+func (s *Server) reply(request *message, result any, err *responseError) error {
+	id := request.ID
+	if id == nil {
+		null := json.RawMessage("null")
+		id = &null
+	}
+	if result == nil && err == nil {
+		result = json.RawMessage("null")
+	}
+	return writeMessage(s.out, &message{ID: id, Result: result, Error: err})
+}
+
+func (s *Server) notify(method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{Method: method, Params: body})
+}
+
+func (s *Server) initialize(json.RawMessage) (any, error) {
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync":           syncFull,
+			"definitionProvider":         true,
+			"referencesProvider":         true,
+			"hoverProvider":              true,
+			"documentSymbolProvider":     true,
+			"documentFormattingProvider": true,
+			"renameProvider":             map[string]bool{"prepareProvider": true},
+			"completionProvider":         map[string]any{"triggerCharacters": []string{"("}},
+		},
+		"serverInfo": map[string]string{"name": "spl"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) error {
+	var p struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+	return s.update(p.TextDocument.URI, p.TextDocument.Text)
+}
+
+// didChange takes the last change, which with full synchronisation holds
+// the whole text.
+func (s *Server) didChange(params json.RawMessage) error {
+	var p struct {
+		TextDocument   TextDocumentIdentifier `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || len(p.ContentChanges) == 0 {
+		return nil
+	}
+	return s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+}
+
+func (s *Server) didClose(params json.RawMessage) error {
+	var p struct {
+		TextDocument TextDocumentIdentifier `json:"textDocument"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil
+	}
+	delete(s.documents, p.TextDocument.URI)
+	delete(s.analyses, p.TextDocument.URI)
+	return s.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri":         p.TextDocument.URI,
+		"diagnostics": []Diagnostic{},
+	})
+}
+
+// update analyses the new text of a document and publishes its
+// diagnostics. Edits of an open document are analysed incrementally.
+func (s *Server) update(uri, text string) error {
+	doc := newDocument(text)
+	s.documents[uri] = doc
+	inc, ok := s.analyses[uri]
+	if !ok {
+		inc = analyser.NewIncremental(text)
+		s.analyses[uri] = inc
+	}
+	analysis := inc.Update(text)
+
+	diagnostics := make([]Diagnostic, len(analysis.Diagnostics))
+	for i, d := range analysis.Diagnostics {
+		diagnostics[i] = Diagnostic{
+			Range:    doc.span(d.Span),
+			Severity: severityError,
+			Source:   "spl",
+			Message:  d.Phase + " error: " + d.Message,
+		}
+	}
+	return s.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
+
+// lookup finds the document of a request and the innermost node at its
+// position, which is nil when the document does not parse.
+func (s *Server) lookup(params json.RawMessage, p any) (*document, *analyser.Analysis, *parser.ASTNode, error) {
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, nil, nil, err
+	}
+	var position TextDocumentPositionParams
+	json.Unmarshal(params, &position)
+	doc, ok := s.documents[position.TextDocument.URI]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("document %s is not open", position.TextDocument.URI)
+	}
+	analysis := s.analyses[position.TextDocument.URI].Analysis()
+	if analysis.Root == nil {
+		return doc, analysis, nil, nil
+	}
+	return doc, analysis, parser.NodeAt(analysis.Root, doc.offset(position.Position)), nil
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	doc, analysis, node, err := s.lookup(params, &p)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	declaration := analysis.Declaration(node)
+	if declaration == nil {
+		return nil, nil
+	}
+	return Location{URI: p.TextDocument.URI, Range: doc.span(declaration.Span)}, nil
+}
+
+func (s *Server) references(params json.RawMessage) (any, error) {
+	var p struct {
+		TextDocumentPositionParams
+		Context struct {
+			IncludeDeclaration bool `json:"includeDeclaration"`
+		} `json:"context"`
+	}
+	doc, analysis, node, err := s.lookup(params, &p)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	locations := make([]Location, 0)
+	for i, reference := range analysis.References(node) {
+		if i > 0 || p.Context.IncludeDeclaration {
+			locations = append(locations, Location{URI: p.TextDocument.URI, Range: doc.span(reference.Span)})
+		}
+	}
+	return locations, nil
+}
+
+// hover describes the identifier at the position, or else the type of
+// the innermost term around it.
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	doc, analysis, node, err := s.lookup(params, &p)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	if symbol, ok := analysis.Describe(node); ok {
+		text := fmt.Sprintf("```spl\n(%s) %s: %s\n```\nBASIC name `%s`", symbol.Kind, symbol.Name, symbol.Type, symbol.BasicName)
+		return Hover{MarkupContent{"markdown", text}, doc.span(node.Span)}, nil
+	}
+	if len(analysis.Diagnostics) > 0 {
+		return nil, nil
+	}
+	offset := doc.offset(p.Position)
+	var term *parser.ASTNode
+	parser.Inspect(analysis.Root, func(n *parser.ASTNode) bool {
+		if !n.Span.IsZero() && !n.Span.Contains(offset) {
+			return false
+		}
+		if n.Type == analyser.TERM {
+			term = n
+		}
+		return true
+	})
+	if t, ok := analysis.TermType(term); ok {
+		text := fmt.Sprintf("```spl\n%s\n```\n%s term", strings.TrimSpace(doc.text[term.Span.Start.Offset:term.Span.End.Offset]), t)
+		return Hover{MarkupContent{"markdown", text}, doc.span(term.Span)}, nil
+	}
+	return nil, nil
+}
+
+// documentSymbol lists the procedures and functions of a document.
+func (s *Server) documentSymbol(params json.RawMessage) (any, error) {
+	var p struct {
+		TextDocument TextDocumentIdentifier `json:"textDocument"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, fmt.Errorf("document %s is not open", p.TextDocument.URI)
+	}
+	symbols := make([]DocumentSymbol, 0)
+	root := s.analyses[p.TextDocument.URI].Analysis().Root
+	if root == nil {
+		return symbols, nil
+	}
+	for i, detail := range []string{"procedure", "function"} {
+		for _, def := range root.Children[i+1].Children {
+			name := def.Children[0]
+			symbols = append(symbols, DocumentSymbol{
+				Name:           name.Name,
+				Detail:         detail,
+				Kind:           symbolFunction,
+				Range:          doc.span(def.Span),
+				SelectionRange: doc.span(name.Span),
+			})
+		}
+	}
+	return symbols, nil
+}
+
+// formatting replaces the whole document with its canonical form. A
+// document with syntax errors is left alone.
+func (s *Server) formatting(params json.RawMessage) (any, error) {
+	var p struct {
+		TextDocument TextDocumentIdentifier `json:"textDocument"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, fmt.Errorf("document %s is not open", p.TextDocument.URI)
+	}
+	edits := make([]TextEdit, 0)
+	root := s.analyses[p.TextDocument.URI].Analysis().Root
+	if root == nil {
+		return edits, nil
+	}
+	formatted, err := parser.FormatSource(root, lexer.Comments(doc.text))
+	if err != nil {
+		return nil, err
+	}
+	if formatted != doc.text {
+		edits = append(edits, TextEdit{Range: doc.whole(), NewText: formatted})
+	}
+	return edits, nil
+}
+
+// prepareRename returns the range of the identifier at the position, or
+// null where there is nothing to rename.
+func (s *Server) prepareRename(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	doc, analysis, node, err := s.lookup(params, &p)
+	if err != nil || analysis.Declaration(node) == nil {
+		return nil, err
+	}
+	return doc.span(node.Span), nil
+}
+
+// rename renames like analyser.Analysis.Rename; a refused rename is an
+// error the client shows.
+func (s *Server) rename(params json.RawMessage) (any, error) {
+	var p struct {
+		TextDocumentPositionParams
+		NewName string `json:"newName"`
+	}
+	doc, analysis, node, err := s.lookup(params, &p)
+	if err != nil {
+		return nil, err
+	}
+	_, spans, err := analysis.Rename(node, p.NewName)
+	if err != nil {
+		return nil, err
+	}
+	edits := make([]TextEdit, len(spans))
+	for i, span := range spans {
+		edits[i] = TextEdit{Range: doc.span(span), NewText: p.NewName}
+	}
+	return map[string]any{"changes": map[string][]TextEdit{p.TextDocument.URI: edits}}, nil
+}
+
+// completionKinds maps the kinds of analyser.Completion to the protocol's.
+var completionKinds = map[string]int{
+	"variable":  completionVariable,
+	"procedure": completionFunction,
+	"function":  completionFunction,
+	"keyword":   completionKeyword,
+}
+
+// completion works on the current text whether or not it parses.
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, fmt.Errorf("document %s is not open", p.TextDocument.URI)
+	}
+	items := make([]CompletionItem, 0)
+	for _, completion := range analyser.Complete(doc.text, doc.offset(p.Position)) {
+		detail := completion.Detail
+		if detail == "" && completion.Kind != "keyword" {
+			detail = completion.Kind
+		}
+		items = append(items, CompletionItem{completion.Label, completionKinds[completion.Kind], detail})
+	}
+	return items, nil
+}
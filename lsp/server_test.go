@@ -0,0 +1,249 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///test.spl"
+
+const testProgram = `glob { g }
+proc {
+  p(a) {
+    local { }
+    g = a
+  }
+}
+func { }
+main {
+  var { x }
+  x = 1;
+  p(x)
+}
+`
+
+// session sends the given requests and notifications, numbering the
+// requests from 1, and returns every message the server wrote.
+func session(t *testing.T, calls ...any) []*message {
+	t.Helper()
+	var in bytes.Buffer
+	id := 0
+	for _, call := range calls {
+		c := call.([2]any)
+		params, err := json.Marshal(c[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		method := c[0].(string)
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","method":%q,"params":%s}`, method, params)
+		if !strings.HasPrefix(method, "textDocument/did") && method != "exit" {
+			id++
+			body = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, id, method, params)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	var out bytes.Buffer
+	if err := Serve(&in, &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	messages := make([]*message, 0)
+	reader := bufio.NewReader(&out)
+	for {
+		msg, err := readMessage(reader)
+		if msg == nil {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func opened(text string) [2]any {
+	return [2]any{"textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": testURI, "languageId": "spl", "version": 1, "text": text},
+	}}
+}
+
+func at(method string, line, character int) [2]any {
+	return [2]any{method, map[string]any{
+		"textDocument": map[string]string{"uri": testURI},
+		"position":     Position{line, character},
+		"context":      map[string]bool{"includeDeclaration": true},
+	}}
+}
+
+func whole(method string) [2]any {
+	return [2]any{method, map[string]any{"textDocument": map[string]string{"uri": testURI}}}
+}
+
+// result decodes the result of the response with the given ID.
+func result(t *testing.T, messages []*message, id int, v any) {
+	t.Helper()
+	for _, msg := range messages {
+		if msg.ID != nil && string(*msg.ID) == fmt.Sprint(id) {
+			if msg.Error != nil {
+				t.Fatalf("request %d failed: %s", id, msg.Error.Message)
+			}
+			body, _ := json.Marshal(msg.Result)
+			if err := json.Unmarshal(body, v); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatalf("no response to request %d", id)
+}
+
+func TestServer(t *testing.T) {
+	messages := session(t,
+		[2]any{"initialize", map[string]any{}},
+		opened(testProgram),
+		at("textDocument/definition", 11, 4),
+		at("textDocument/references", 4, 4),
+		at("textDocument/hover", 4, 8),
+		whole("textDocument/documentSymbol"),
+		whole("textDocument/formatting"),
+		[2]any{"shutdown", nil},
+		[2]any{"exit", nil},
+	)
+
+	var capabilities struct {
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	result(t, messages, 1, &capabilities)
+	if capabilities.Capabilities["definitionProvider"] != true {
+		t.Errorf("got capabilities %v", capabilities.Capabilities)
+	}
+
+	var definition Location
+	result(t, messages, 2, &definition)
+	if definition.URI != testURI || definition.Range != (Range{Position{9, 8}, Position{9, 9}}) {
+		t.Errorf("got definition %+v, expected x in the var section", definition)
+	}
+
+	var references []Location
+	result(t, messages, 3, &references)
+	if len(references) != 2 || references[0].Range.Start != (Position{0, 7}) || references[1].Range.Start != (Position{4, 4}) {
+		t.Errorf("got references %+v, expected the global and its use", references)
+	}
+
+	var hover Hover
+	result(t, messages, 4, &hover)
+	if !strings.Contains(hover.Contents.Value, "(parameter) a: numeric") || !strings.Contains(hover.Contents.Value, "BASIC name `c`") {
+		t.Errorf("got hover %q", hover.Contents.Value)
+	}
+
+	var symbols []DocumentSymbol
+	result(t, messages, 5, &symbols)
+	if len(symbols) != 1 || symbols[0].Name != "p" || symbols[0].Detail != "procedure" || symbols[0].Range.Start != (Position{2, 2}) {
+		t.Errorf("got symbols %+v", symbols)
+	}
+
+	var edits []TextEdit
+	result(t, messages, 6, &edits)
+	if len(edits) != 0 {
+		t.Errorf("got edits %+v for a formatted program", edits)
+	}
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	messages := session(t,
+		opened("glob { }\nproc { }\nfunc { }\nmain {\n  var { }\n  y = 1\n}\n"),
+		[2]any{"textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": testURI, "version": 2},
+			"contentChanges": []map[string]string{{"text": "glob { }\nproc { }\nfunc { }\nmain { var { } halt }"}},
+		}},
+		whole("textDocument/formatting"),
+	)
+
+	var published []struct {
+		URI         string       `json:"uri"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+	for _, msg := range messages {
+		if msg.Method == "textDocument/publishDiagnostics" {
+			var params struct {
+				URI         string       `json:"uri"`
+				Diagnostics []Diagnostic `json:"diagnostics"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			published = append(published, params)
+		}
+	}
+	if len(published) != 2 {
+		t.Fatalf("got %d diagnostics notifications, expected 2", len(published))
+	}
+	if d := published[0].Diagnostics; len(d) != 1 || d[0].Message != "naming error: undeclared-variable: y" || d[0].Range.Start != (Position{5, 2}) {
+		t.Errorf("got diagnostics %+v", d)
+	}
+	if d := published[1].Diagnostics; len(d) != 0 {
+		t.Errorf("got diagnostics %+v after the fix", d)
+	}
+
+	var edits []TextEdit
+	result(t, messages, 1, &edits)
+	if len(edits) != 1 || !strings.HasPrefix(edits[0].NewText, "glob { }\nproc { }\nfunc { }\nmain {\n  var { }\n  halt\n}") {
+		t.Errorf("got edits %+v", edits)
+	}
+}
+
+func TestServerRename(t *testing.T) {
+	rename := func(line, character int, name string) [2]any {
+		return [2]any{"textDocument/rename", map[string]any{
+			"textDocument": map[string]string{"uri": testURI},
+			"position":     Position{line, character},
+			"newName":      name,
+		}}
+	}
+	messages := session(t,
+		opened(testProgram),
+		at("textDocument/prepareRename", 11, 4),
+		rename(11, 4, "count"),
+		rename(11, 4, "while"),
+	)
+
+	var prepared Range
+	result(t, messages, 1, &prepared)
+	if prepared != (Range{Position{11, 4}, Position{11, 5}}) {
+		t.Errorf("got prepared range %+v", prepared)
+	}
+
+	var edit struct {
+		Changes map[string][]TextEdit `json:"changes"`
+	}
+	result(t, messages, 2, &edit)
+	edits := edit.Changes[testURI]
+	if len(edits) != 3 || edits[0].Range.Start != (Position{9, 8}) || edits[0].NewText != "count" {
+		t.Errorf("got edits %+v", edits)
+	}
+
+	for _, msg := range messages {
+		if msg.ID != nil && string(*msg.ID) == "3" && (msg.Error == nil || msg.Error.Message != "while is a keyword") {
+			t.Errorf("got %+v, expected a refused rename", msg.Error)
+		}
+	}
+}
+
+func TestServerCompletion(t *testing.T) {
+	messages := session(t,
+		opened("glob { g }\nproc { }\nfunc { f(n) { local { } halt; return n } }\nmain {\n  var { x }\n  x = \n"),
+		at("textDocument/completion", 5, 6),
+	)
+	var items []CompletionItem
+	result(t, messages, 1, &items)
+	expected := []CompletionItem{
+		{"x", completionVariable, "variable"},
+		{"g", completionVariable, "global variable"},
+		{"f", completionFunction, "function"},
+	}
+	if fmt.Sprint(items) != fmt.Sprint(expected) {
+		t.Errorf("got %v, expected %v", items, expected)
+	}
+}
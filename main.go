@@ -1,18 +1,79 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"html/template"
 	"os"
+	"strconv"
 	"strings"
-	"time"
 
 	"SPL-compiler/analyser"
+	"SPL-compiler/backend"
 	"SPL-compiler/lexer"
+	"SPL-compiler/lsp"
 	"SPL-compiler/parser"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "renumber" {
+		renumber(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ast" {
+		ast(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		format(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rename" {
+		rename(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Language server error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		run(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "dot" {
+		dot(os.Args[2:])
+		return
+	}
+
+	targets := flag.String("target", "basic,html",
+		"comma-separated list of backends to run ("+strings.Join(backend.Names(), ", ")+")")
+	dialect := flag.String("dialect", "spl",
+		"BASIC dialect ("+strings.Join(analyser.BasicDialectNames(), ", ")+")")
+	lineStart := flag.Int("line-start", analyser.DefaultLineNumbering.Start, "first BASIC line number")
+	lineIncrement := flag.Int("line-increment", analyser.DefaultLineNumbering.Increment, "step between BASIC line numbers")
+	lineMax := flag.Int("line-max", 0, "highest BASIC line number allowed, 0 for the dialect's limit")
+	remComments := flag.Bool("rem-comments", false, "end generated BASIC lines with a REM naming their SPL line")
+	sourceComments := flag.Bool("source-comments", false, "copy the comments of the SPL source into generated BASIC as REM lines")
+	checked := flag.Bool("checked", false, "halt generated BASIC with a diagnostic on integer overflow")
+	flag.Parse()
+
+	if _, err := analyser.LookupBasicDialect(*dialect); err != nil {
+		fmt.Println("Target error:", err)
+		return
+	}
+
+	backends := make([]backend.Backend, 0)
+	for _, target := range strings.Split(*targets, ",") {
+		b, err := backend.Lookup(strings.TrimSpace(target))
+		if err != nil {
+			fmt.Println("Target error:", err)
+			return
+		}
+		backends = append(backends, b)
+	}
+
 	filename := getFilenameFromUser()
 	program := readFromFile(filename)
 	if lexer.Validate(program) {
@@ -52,26 +113,41 @@ func main() {
 		fmt.Println("No Recursion detected")
 	}
 
-	intermediateCode, err := analyser.ValidateCodeGeneration(root)
+	intermediateCode, origins, err := analyser.ValidateCodeGeneration(root)
 	if err != nil {
 		fmt.Println("Intermediate Code Generation error:", err)
 		return
 	}
-	generateHTML(intermediateCode, "output.html")
 
-	basicCode, err := analyser.ValidateTranslateToBasic(intermediateCode)
-	if err != nil {
-		fmt.Println("BASIC Code Translation error:", err)
-		return
-	} else {
-		writeToFile("output.txt", strings.Join(basicCode, "\n"))
-		fmt.Println("Basic code generated successfully")
+	compiled := &backend.Program{
+		Filename:     filename,
+		Source:       program,
+		AST:          root,
+		Intermediate: intermediateCode,
+		Origins:      origins,
+		Options: map[string]string{
+			"dialect":         *dialect,
+			"line-start":      strconv.Itoa(*lineStart),
+			"line-increment":  strconv.Itoa(*lineIncrement),
+			"line-max":        strconv.Itoa(*lineMax),
+			"checked":         strconv.FormatBool(*checked),
+			"rem-comments":    strconv.FormatBool(*remComments),
+			"source-comments": strconv.FormatBool(*sourceComments),
+		}}
+	for _, b := range backends {
+		code, err := b.Emit(compiled)
+		if err != nil {
+			fmt.Printf("%s Code Translation error: %v\n", b.Name(), err)
+			return
+		}
+		writeToFile("output."+b.FileExtension(), string(code))
+		fmt.Printf("%s code generated successfully\n", b.Name())
 	}
 }
 
 func getFilenameFromUser() string {
-	if len(os.Args) > 1 {
-		return os.Args[1]
+	if flag.NArg() > 0 {
+		return flag.Arg(0)
 	}
 	var filename string
 	fmt.Print("Enter the filename of the SPL program (with the .txt at the end): ")
@@ -93,73 +169,3 @@ func writeToFile(filename string, content string) {
 		fmt.Printf("Error writing file: %v\n", err)
 	}
 }
-
-// WriteInstructionsHTML writes a standalone HTML file displaying the given instructions.
-// filename should include .html (e.g. "instructions.html").
-// Returns an error if writing/parsing fails.
-func generateHTML(instructions []string, filename string) error {
-	const tpl = `<!DOCTYPE html>
-<html lang="en">
-<head>
-<meta charset="UTF-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0">
-<title>Intermediate Instructions</title>
-<style>
-  body {
-    font-family: "JetBrains Mono", monospace;
-    background-color: #f9fafb;
-    color: #111827;
-    margin: 40px;
-  }
-  h1 {
-    text-align: center;
-  }
-  .meta {
-    text-align: center;
-    color: #6b7280;
-    font-size: 0.9em;
-    margin-bottom: 20px;
-  }
-  pre {
-    background-color: #f3f4f6;
-    padding: 16px;
-    border-radius: 8px;
-    overflow-x: auto;
-    line-height: 1.6;
-  }
-  .line {
-    counter-increment: line;
-  }
-  .line::before {
-    content: counter(line) ": ";
-    color: #9ca3af;
-  }
-</style>
-</head>
-<body>
-  <h1>Intermediate Instructions</h1>
-  <pre>
-{{range .Instructions}}<div class="line">{{.}}</div>
-{{end}}
-  </pre>
-</body>
-</html>`
-
-	t := template.Must(template.New("instructions").Parse(tpl))
-
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	data := struct {
-		Instructions []string
-		GeneratedAt  string
-	}{
-		Instructions: instructions,
-		GeneratedAt:  time.Now().Format("2006-01-02 15:04:05"),
-	}
-
-	return t.Execute(file, data)
-}
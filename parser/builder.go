@@ -0,0 +1,207 @@
+package parser
+
+// The grammar actions build the typed AST with the constructors below. Each
+// constructor makes the generic node its typed node views, together with
+// the wrapper nodes the generic tree has and the typed AST does not: the
+// section lists, PARAM, BODY, MAXTHREE, ALGO, INPUT and OUTPUT, the ASSIGN,
+// LOOP and BRANCH under an instruction, and the TERM and ATOM around atoms.
+
+// block is an algorithm as the grammar reads it, before the ALGO node of its
+// owner is made. A function body's span reaches its last semicolon.
+type block struct {
+	stmts []Stmt
+	span  Span
+}
+
+func (b block) add(stmt Stmt) block {
+	b.stmts = append(b.stmts, stmt)
+	b.span = join(b.span, stmt.Span())
+	return b
+}
+
+// definitionBody is the local declarations and algorithm of a procedure or
+// function.
+type definitionBody struct {
+	locals     []*VarDecl
+	localsSpan Span
+	algo       block
+	span       Span
+}
+
+func newProgram(globals []*VarDecl, procs []*ProcDef, funcs []*FuncDef, main *Main, sections [4]Span, span Span) *Program {
+	var procNodes, funcNodes []*ASTNode
+	for _, proc := range procs {
+		procNodes = append(procNodes, proc.Generic())
+	}
+	for _, fn := range funcs {
+		funcNodes = append(funcNodes, fn.Generic())
+	}
+	main.node.At(sections[3], sections[3])
+	node := NewNode("SPL_PROG", "",
+		variablesNode(globals).At(sections[0], sections[0]),
+		NewNode("PROCDEFS", "", procNodes...).At(sections[1], sections[1]),
+		NewNode("FUNCDEFS", "", funcNodes...).At(sections[2], sections[2]),
+		main.node,
+	).At(span, span)
+	return &Program{view{node}, globals, procs, funcs, main}
+}
+
+func newVarDecl(name string, span Span) *VarDecl {
+	return &VarDecl{view{NewNode("VAR", name).At(span, span)}, name}
+}
+
+func newIdent(name string, span Span) *Ident {
+	return &Ident{view{NewNode("NAME", name).At(span, span)}, name}
+}
+
+func newProcDef(name *Ident, params []*VarDecl, body definitionBody, end Span) *ProcDef {
+	node := NewNode("PDEF", "", name.node, paramNode(params), bodyNode(body)).At(name.Span(), end)
+	return &ProcDef{view{node}, name, params, body.locals, body.algo.stmts}
+}
+
+func newFuncDef(name *Ident, params []*VarDecl, body definitionBody, result Atom, end Span) *FuncDef {
+	node := NewNode("FDEF", "", name.node, paramNode(params), bodyNode(body), atomNode(result)).At(name.Span(), end)
+	return &FuncDef{view{node}, name, params, body.locals, body.algo.stmts, result}
+}
+
+func newMain(vars []*VarDecl, varsSpan Span, body block, start Span) *Main {
+	node := NewNode("MAINPROG", "", variablesNode(vars).At(varsSpan, varsSpan), algoNode(body)).At(start, body.span)
+	return &Main{view{node}, vars, body.stmts}
+}
+
+func newHalt(span Span) *Halt {
+	return &Halt{view{NewNode("INSTR", "halt").At(span, span)}}
+}
+
+// newPrint prints value, an Atom or a *StringLit.
+func newPrint(value Node, start Span) *Print {
+	output := value.Generic()
+	if atom, ok := value.(Atom); ok {
+		output = NewNode("OUTPUT", "atom", atomNode(atom))
+	}
+	return &Print{view{NewNode("INSTR", "print", output).At(start, output.Span)}, value}
+}
+
+func newStringLit(value string, span Span) *StringLit {
+	return &StringLit{view{NewNode("OUTPUT", value).At(span, span)}, value}
+}
+
+func newCallStmt(name *Ident, args []Atom, end Span) *CallStmt {
+	node := NewNode("INSTR", "call", name.node, inputNode(args)).At(name.Span(), end)
+	return &CallStmt{view{node}, name, args}
+}
+
+func newAssign(target *VarRef, value Expr) *Assign {
+	node := NewNode("INSTR", "assign", NewNode("ASSIGN", "", target.node, termNode(value)))
+	return &Assign{view{node}, target, value}
+}
+
+func newCallAssign(target *VarRef, name *Ident, args []Atom, end Span) *CallAssign {
+	assign := NewNode("ASSIGN", "call", target.node, name.node, inputNode(args)).At(target.Span(), end)
+	return &CallAssign{view{NewNode("INSTR", "assign", assign)}, target, name, args}
+}
+
+func newWhile(cond Expr, body block, start, end Span) *While {
+	loop := NewNode("LOOP", "while", termNode(cond), algoNode(body)).At(start, end)
+	return &While{view{NewNode("INSTR", "loop", loop)}, cond, body.stmts}
+}
+
+func newDoUntil(body block, cond Expr, start Span) *DoUntil {
+	loop := NewNode("LOOP", "do", algoNode(body), termNode(cond)).At(start, cond.Span())
+	return &DoUntil{view{NewNode("INSTR", "loop", loop)}, body.stmts, cond}
+}
+
+func newIf(cond Expr, then block, start, end Span) *If {
+	branch := NewNode("BRANCH", "if", termNode(cond), algoNode(then)).At(start, end)
+	return &If{view{NewNode("INSTR", "branch", branch)}, cond, then.stmts, nil}
+}
+
+func newIfElse(cond Expr, then, otherwise block, start, end Span) *If {
+	branch := NewNode("BRANCH", "ifelse", termNode(cond), algoNode(then), algoNode(otherwise)).At(start, end)
+	return &If{view{NewNode("INSTR", "branch", branch)}, cond, then.stmts, otherwise.stmts}
+}
+
+func newUnaryExpr(op string, opSpan Span, operand Expr, start, end Span) *UnaryExpr {
+	node := NewNode("TERM", "unop", NewNode("UNOP", op).At(opSpan, opSpan), termNode(operand)).At(start, end)
+	return &UnaryExpr{view{node}, op, operand}
+}
+
+func newBinaryExpr(left Expr, op string, opSpan Span, right Expr, start, end Span) *BinaryExpr {
+	node := NewNode("TERM", "binop", termNode(left), NewNode("BINOP", op).At(opSpan, opSpan), termNode(right)).At(start, end)
+	return &BinaryExpr{view{node}, op, left, right}
+}
+
+func newVarRef(name string, span Span) *VarRef {
+	return &VarRef{view{NewNode("VAR", name).At(span, span)}, name}
+}
+
+func newNumberLit(value string, span Span) *NumberLit {
+	return &NumberLit{view{NewNode("ATOM", value).At(span, span)}, value}
+}
+
+func variablesNode(vars []*VarDecl) *ASTNode {
+	return NewNode("VARIABLES", "", declNodes(vars)...)
+}
+
+// maxThreeNode is named "empty" when there are no variables.
+func maxThreeNode(vars []*VarDecl) *ASTNode {
+	if len(vars) == 0 {
+		return NewNode("MAXTHREE", "empty")
+	}
+	return NewNode("MAXTHREE", "", declNodes(vars)...)
+}
+
+// declNodes and the other list helpers leave an empty list nil, as NewNode
+// without children does.
+func declNodes(vars []*VarDecl) []*ASTNode {
+	var nodes []*ASTNode
+	for _, v := range vars {
+		nodes = append(nodes, v.node)
+	}
+	return nodes
+}
+
+func paramNode(params []*VarDecl) *ASTNode {
+	return NewNode("PARAM", "", maxThreeNode(params))
+}
+
+func bodyNode(body definitionBody) *ASTNode {
+	locals := maxThreeNode(body.locals).At(body.localsSpan, body.localsSpan)
+	return NewNode("BODY", "", locals, algoNode(body.algo)).At(body.span, body.algo.span)
+}
+
+func algoNode(b block) *ASTNode {
+	var nodes []*ASTNode
+	for _, stmt := range b.stmts {
+		nodes = append(nodes, stmt.Generic())
+	}
+	return NewNode("ALGO", "", nodes...).At(b.span, b.span)
+}
+
+// inputNode is named "empty" when there are no arguments.
+func inputNode(args []Atom) *ASTNode {
+	if len(args) == 0 {
+		return NewNode("INPUT", "empty")
+	}
+	var nodes []*ASTNode
+	for _, arg := range args {
+		nodes = append(nodes, atomNode(arg))
+	}
+	return NewNode("INPUT", "", nodes...)
+}
+
+// termNode wraps an atom in the TERM node the generic tree has for it.
+func termNode(expr Expr) *ASTNode {
+	if atom, ok := expr.(Atom); ok {
+		return NewNode("TERM", "atom", atomNode(atom))
+	}
+	return expr.Generic()
+}
+
+// atomNode wraps a variable in the ATOM node the generic tree has for it.
+func atomNode(atom Atom) *ASTNode {
+	if ref, ok := atom.(*VarRef); ok {
+		return NewNode("ATOM", "Var", ref.node)
+	}
+	return atom.Generic()
+}
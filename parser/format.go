@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"SPL-compiler/lexer"
+	"fmt"
+	"strings"
+)
+
+// FormatSource prints a program as canonically formatted SPL: two spaces
+// of indentation per block, one instruction per line, definitions on lines
+// of their own and single spaces inside terms. Formatting the parse of the
+// result gives the result again.
+//
+// The comments, as returned by lexer.Comments for the source of root, are
+// kept in order: a comment on a line of its own goes before the first line
+// printed for the code after it, one following code on the same line ends
+// the line printed last.
+//
+// A tree that does not have the shape the parser produces is an error
+// rather than a panic.
+func FormatSource(root *ASTNode, comments []lexer.Comment) (source string, err error) {
+	program, err := ValidateTyped(root)
+	if err != nil {
+		return "", err
+	}
+	return FormatProgram(program, comments), nil
+}
+
+// FormatProgram prints the typed AST of a program like FormatSource.
+func FormatProgram(program *Program, comments []lexer.Comment) string {
+	f := &formatter{comments: comments}
+	f.program(program)
+	f.flush(-1)
+	return strings.Join(f.lines, "\n") + "\n"
+}
+
+type formatter struct {
+	lines    []string
+	depth    int
+	comments []lexer.Comment
+}
+
+func (f *formatter) line(format string, args ...any) {
+	f.lines = append(f.lines, strings.Repeat("  ", f.depth)+fmt.Sprintf(format, args...))
+}
+
+// flush prints the comments that start before offset, or all of them when
+// offset is negative.
+func (f *formatter) flush(offset int) {
+	for len(f.comments) > 0 && (offset < 0 || f.comments[0].Offset < offset) {
+		comment := f.comments[0]
+		f.comments = f.comments[1:]
+		if comment.Trailing && len(f.lines) > 0 {
+			f.lines[len(f.lines)-1] += " " + comment.Text
+		} else {
+			f.line("%s", comment.Text)
+		}
+	}
+}
+
+func (f *formatter) program(program *Program) {
+	sections := program.Generic().Children
+	f.flush(sections[0].Span.Start.Offset)
+	f.line("glob %s", declarations(program.Globals))
+
+	f.flush(sections[1].Span.Start.Offset)
+	if len(program.Procs) == 0 {
+		f.line("proc { }")
+	} else {
+		f.line("proc {")
+		f.depth++
+		for _, proc := range program.Procs {
+			f.definition(proc.Generic(), proc.Name, proc.Params, proc.Locals, proc.Body, nil)
+		}
+		f.flush(sections[1].Span.End.Offset)
+		f.depth--
+		f.line("}")
+	}
+
+	f.flush(sections[2].Span.Start.Offset)
+	if len(program.Funcs) == 0 {
+		f.line("func { }")
+	} else {
+		f.line("func {")
+		f.depth++
+		for _, fn := range program.Funcs {
+			f.definition(fn.Generic(), fn.Name, fn.Params, fn.Locals, fn.Body, fn.Return)
+		}
+		f.flush(sections[2].Span.End.Offset)
+		f.depth--
+		f.line("}")
+	}
+
+	main := program.Main.Generic()
+	f.flush(sections[3].Span.Start.Offset)
+	f.line("main {")
+	f.depth++
+	f.flush(main.Children[0].Span.Start.Offset)
+	f.line("var %s", declarations(program.Main.Vars))
+	f.algo(program.Main.Body, false)
+	f.flush(sections[3].Span.End.Offset)
+	f.depth--
+	f.line("}")
+}
+
+// definition prints a procedure, or a function when result is not nil.
+// Every instruction of a function body ends with a semicolon.
+func (f *formatter) definition(def *ASTNode, name *Ident, params, locals []*VarDecl, body []Stmt, result Atom) {
+	f.flush(def.Span.Start.Offset)
+	f.line("%s(%s) {", name.Name, strings.Join(declarationNames(params), " "))
+	f.depth++
+	f.flush(def.Children[2].Children[0].Span.Start.Offset)
+	f.line("local %s", declarations(locals))
+	f.algo(body, result != nil)
+	if result != nil {
+		f.flush(result.Span().Start.Offset)
+		f.line("return %s", atom(result))
+	}
+	f.flush(def.Span.End.Offset)
+	f.depth--
+	f.line("}")
+}
+
+func (f *formatter) algo(body []Stmt, terminated bool) {
+	for i, stmt := range body {
+		separator := ";"
+		if i == len(body)-1 && !terminated {
+			separator = ""
+		}
+		f.stmt(stmt, separator)
+	}
+}
+
+// stmt prints an instruction followed by separator, which for blocks comes
+// after the closing brace.
+func (f *formatter) stmt(stmt Stmt, separator string) {
+	span := stmt.Span()
+	f.flush(span.Start.Offset)
+	switch s := stmt.(type) {
+	case *Halt:
+		f.line("halt%s", separator)
+	case *Print:
+		if str, ok := s.Value.(*StringLit); ok {
+			f.line("print \"%s\"%s", str.Value, separator)
+		} else {
+			f.line("print %s%s", atom(s.Value.(Atom)), separator)
+		}
+	case *CallStmt:
+		f.line("%s(%s)%s", s.Name.Name, atoms(s.Args), separator)
+	case *Assign:
+		f.line("%s = %s%s", s.Target.Name, term(s.Value), separator)
+	case *CallAssign:
+		f.line("%s = %s(%s)%s", s.Target.Name, s.Name.Name, atoms(s.Args), separator)
+	case *While:
+		f.line("while %s {", term(s.Cond))
+		f.block(s.Body, span.End.Offset)
+		f.line("}%s", separator)
+	case *DoUntil:
+		f.line("do {")
+		f.block(s.Body, s.Cond.Span().Start.Offset)
+		f.line("} until %s%s", term(s.Cond), separator)
+	case *If:
+		f.line("if %s {", term(s.Cond))
+		if s.Else != nil {
+			f.block(s.Then, s.Else[0].Span().Start.Offset)
+			f.line("} else {")
+			f.block(s.Else, span.End.Offset)
+		} else {
+			f.block(s.Then, span.End.Offset)
+		}
+		f.line("}%s", separator)
+	default:
+		panic(fmt.Sprintf("cannot format %T", stmt))
+	}
+}
+
+// block prints an indented algorithm followed by the comments before end,
+// the offset of the code closing it.
+func (f *formatter) block(body []Stmt, end int) {
+	f.depth++
+	f.algo(body, false)
+	f.flush(end)
+	f.depth--
+}
+
+func declarationNames(vars []*VarDecl) []string {
+	names := make([]string, len(vars))
+	for i, v := range vars {
+		names[i] = v.Name
+	}
+	return names
+}
+
+// declarations prints a braced declaration list.
+func declarations(vars []*VarDecl) string {
+	if len(vars) == 0 {
+		return "{ }"
+	}
+	return "{ " + strings.Join(declarationNames(vars), " ") + " }"
+}
+
+func atoms(args []Atom) string {
+	output := make([]string, len(args))
+	for i, arg := range args {
+		output[i] = atom(arg)
+	}
+	return strings.Join(output, " ")
+}
+
+func atom(a Atom) string {
+	switch a := a.(type) {
+	case *VarRef:
+		return a.Name
+	case *NumberLit:
+		return a.Value
+	default:
+		panic(fmt.Sprintf("cannot format %T", a))
+	}
+}
+
+func term(e Expr) string {
+	switch e := e.(type) {
+	case *UnaryExpr:
+		return fmt.Sprintf("(%s %s)", e.Op, term(e.Operand))
+	case *BinaryExpr:
+		return fmt.Sprintf("(%s %s %s)", term(e.Left), e.Op, term(e.Right))
+	case Atom:
+		return atom(e)
+	default:
+		panic(fmt.Sprintf("cannot format %T", e))
+	}
+}
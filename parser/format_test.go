@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"SPL-compiler/lexer"
+	"os"
+	"testing"
+)
+
+func TestFormatSource(t *testing.T) {
+	input := `glob {x}
+proc { p(a) { local {} if (a>0) { print a } else { halt } } }
+func { f(n) { local { r } r = (neg n); do { r = ( r plus 1 ) } until (not(r > 0)); return r } }
+main { var { } x = f(2); while (x eq 0) { p(x) ; print "done" } }`
+	expected := `glob { x }
+proc {
+  p(a) {
+    local { }
+    if (a > 0) {
+      print a
+    } else {
+      halt
+    }
+  }
+}
+func {
+  f(n) {
+    local { r }
+    r = (neg n);
+    do {
+      r = (r plus 1)
+    } until (not (r > 0));
+    return r
+  }
+}
+main {
+  var { }
+  x = f(2);
+  while (x eq 0) {
+    p(x);
+    print "done"
+  }
+}
+`
+	root, err := Validate(input)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	formatted, err := FormatSource(root, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if formatted != expected {
+		t.Errorf("got\n%s\nexpected\n%s", formatted, expected)
+	}
+}
+
+func TestFormatSourceIdempotent(t *testing.T) {
+	for _, filename := range []string{"../basic.txt", "../euklids.txt", "../simpleProg.txt"} {
+		t.Run(filename, func(t *testing.T) {
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			root, err := Validate(string(content))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			once, err := FormatSource(root, lexer.Comments(string(content)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reparsed, err := Validate(once)
+			if err != nil {
+				t.Fatalf("formatted program does not parse: %v\n%s", err, once)
+			}
+			if twice, _ := FormatSource(reparsed, lexer.Comments(once)); twice != once {
+				t.Errorf("formatting is not idempotent:\n%s\nbecame\n%s", once, twice)
+			}
+			if formatShape(root) != formatShape(reparsed) {
+				t.Errorf("formatting changed the tree")
+			}
+		})
+	}
+
+	if content, _ := os.ReadFile("../euklids.txt"); content != nil {
+		root, _ := Validate(string(content))
+		if formatted, _ := FormatSource(root, lexer.Comments(string(content))); formatted != string(content) {
+			t.Errorf("euklids.txt is no longer canonically formatted")
+		}
+	}
+}
+
+// formatShape renders a tree without IDs or positions.
+func formatShape(node *ASTNode) string {
+	shape := node.Type + ":" + node.Name + "("
+	for _, child := range node.Children {
+		shape += formatShape(child)
+	}
+	return shape + ")"
+}
+
+func TestFormatSourceComments(t *testing.T) {
+	input := `// header
+glob { x } // globals
+/* no procedures
+   yet */
+proc { }
+func { }
+main {
+var { }
+// set x
+x = 1; /* inline */ print x;
+while (x > 0) { // count down
+x = (x minus 1)
+// last
+}
+}
+// trailer`
+	expected := `// header
+glob { x } // globals
+/* no procedures
+   yet */
+proc { }
+func { }
+main {
+  var { }
+  // set x
+  x = 1; /* inline */
+  print x;
+  while (x > 0) { // count down
+    x = (x minus 1)
+    // last
+  }
+}
+// trailer
+`
+	root, err := Validate(input)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	formatted, err := FormatSource(root, lexer.Comments(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if formatted != expected {
+		t.Errorf("got\n%s\nexpected\n%s", formatted, expected)
+	}
+	reparsed, err := Validate(formatted)
+	if err != nil {
+		t.Fatalf("formatted program does not parse: %v", err)
+	}
+	if again, _ := FormatSource(reparsed, lexer.Comments(formatted)); again != formatted {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormatSourceStrings(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Testing a string named like an atom output", `print "atom"`, `print "atom"`},
+		{"Testing a string named like a call", `print "call"`, `print "call"`},
+		{"Testing a string named like an instruction", `print "halt"; halt`, "print \"halt\";\n  halt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := Validate("glob { }\nproc { }\nfunc { }\nmain { var { } " + tt.input + " }")
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			formatted, err := FormatSource(root, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := "glob { }\nproc { }\nfunc { }\nmain {\n  var { }\n  " + tt.expected + "\n}\n"
+			if formatted != expected {
+				t.Errorf("got\n%s\nexpected\n%s", formatted, expected)
+			}
+		})
+	}
+}
+
+func TestFormatSourceMalformed(t *testing.T) {
+	root := NewNode("SPL_PROG", "", NewNode("VARIABLES", ""))
+	if _, err := FormatSource(root, nil); err == nil {
+		t.Errorf("expected an error for a malformed tree")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"SPL-compiler/lexer"
 )
@@ -30,7 +31,7 @@ func GenerateAST(input string) *ASTNode {
 	}
 
 	if result != nil {
-		fmt.Println("Parsing finished, AST generated.")
+		fmt.Fprintln(os.Stderr, "Parsing finished, AST generated.")
 	} else {
 		fmt.Fprintf(os.Stderr, "Parsing finished, but no AST was generated.\n")
 	}
@@ -38,7 +39,58 @@ func GenerateAST(input string) *ASTNode {
 	return ResultAST
 }
 
+// SyntaxError is a lexical or syntax error at the token in Span.
+type SyntaxError struct {
+	Message string
+	Span    Span
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Span.Start.Line, e.Span.Start.Column, e.Message)
+}
+
+// ParseSource parses a program like GenerateAST but reports errors as a
+// *SyntaxError instead of printing them, for tools that keep running
+// after a mistake in their input.
+func ParseSource(input string) (root *ASTNode, err error) {
+	program, err := ParseProgram(input)
+	if err != nil {
+		return nil, err
+	}
+	return program.Generic(), nil
+}
+
+// ParseProgram parses a program into its typed AST, reporting errors like
+// ParseSource.
+func ParseProgram(input string) (program *Program, err error) {
+	lexerAdapter := &LexerAdapter{L: lexer.New(input)}
+	defer func() {
+		if r := recover(); r != nil {
+			message := strings.TrimPrefix(fmt.Sprint(r), "Parse error: ")
+			program, err = nil, &SyntaxError{Message: message, Span: lexerAdapter.Last}
+		}
+	}()
+
+	if _, err := Parse(lexerAdapter); err != nil {
+		return nil, &SyntaxError{Message: err.Error(), Span: lexerAdapter.Last}
+	}
+	return lexerAdapter.Program, nil
+}
+
 func PrettyPrintASTNode(n *ASTNode, prefix string, isTail bool) {
+	var b strings.Builder
+	formatASTNode(&b, n, prefix, isTail)
+	fmt.Print(b.String())
+}
+
+// FormatAST returns the tree as PrettyPrintASTNode draws it.
+func FormatAST(root *ASTNode) string {
+	var b strings.Builder
+	formatASTNode(&b, root, "", true)
+	return b.String()
+}
+
+func formatASTNode(b *strings.Builder, n *ASTNode, prefix string, isTail bool) {
 	if n == nil {
 		return
 	}
@@ -47,11 +99,11 @@ func PrettyPrintASTNode(n *ASTNode, prefix string, isTail bool) {
 	if isTail {
 		connector = "└── "
 	}
-	fmt.Printf("%s%s[%d] %s", prefix, connector, n.ID, n.Type)
+	fmt.Fprintf(b, "%s%s[%d] %s", prefix, connector, n.ID, n.Type)
 	if n.Name != "" {
-		fmt.Printf(": %s", n.Name)
+		fmt.Fprintf(b, ": %s", n.Name)
 	}
-	fmt.Println()
+	b.WriteString("\n")
 
 	childPrefix := prefix
 	if isTail {
@@ -62,7 +114,7 @@ func PrettyPrintASTNode(n *ASTNode, prefix string, isTail bool) {
 
 	for i, child := range n.Children {
 		isLast := i == len(n.Children)-1
-		PrettyPrintASTNode(child, childPrefix, isLast)
+		formatASTNode(b, child, childPrefix, isLast)
 	}
 }
 
@@ -7,18 +7,28 @@ import (
 	"SPL-compiler/token"
 )
 
+// LexerAdapter feeds the tokens of L to the parser. After a parse Program
+// holds the typed AST and AST its generic view. Last is the span of the
+// token read last, where a syntax error is reported.
 type LexerAdapter struct {
-	L   *lexer.Lexer
-	AST *ASTNode
+	L       *lexer.Lexer
+	Program *Program
+	AST     *ASTNode
+	Last    Span
 }
 
 func (la *LexerAdapter) Lex(lval *yySymType) int {
 	tok := la.L.NextToken()
+	la.Last = Span{
+		Start: Position{Line: tok.Line, Column: tok.Column, Offset: tok.Offset},
+		End:   Position{Line: tok.Line, Column: tok.Column + tok.EndOffset - tok.Offset, Offset: tok.EndOffset},
+	}
 	if tok.Type == token.ILLEGAL {
 		panic("Lexical error at line " + fmt.Sprint(tok.Line) +
 			", column " + fmt.Sprint(tok.Column) +
 			": '" + tok.Literal + "'")
 	}
+	lval.Pos = la.Last
 
 	switch tok.Type {
 	case token.GLOB:
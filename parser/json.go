@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// ASTSchema identifies the JSON layout written by MarshalAST. It changes
+// whenever a field is renamed, removed or changes meaning.
+const ASTSchema = "spl-ast/1"
+
+// astDocument is the top-level JSON object. Every node is an object with
+// "id", "type", "span" and, when present, "name" and "children".
+type astDocument struct {
+	Schema string   `json:"schema"`
+	Root   *ASTNode `json:"root"`
+}
+
+// MarshalAST encodes a tree as indented JSON.
+func MarshalAST(root *ASTNode) ([]byte, error) {
+	if root == nil {
+		return nil, fmt.Errorf("no AST to encode")
+	}
+	return json.MarshalIndent(astDocument{Schema: ASTSchema, Root: root}, "", "  ")
+}
+
+// UnmarshalAST decodes a program written by MarshalAST or by hand. Nodes
+// need a type and positive IDs that are unique within the tree, and the
+// tree needs the shape the parser produces, as checked by ValidateTyped.
+// Nodes created afterwards get IDs above the largest one read.
+func UnmarshalAST(data []byte) (*ASTNode, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var document astDocument
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid AST JSON: %v", err)
+	}
+	if document.Schema != ASTSchema {
+		return nil, fmt.Errorf("unsupported AST schema %q, expected %q", document.Schema, ASTSchema)
+	}
+	if document.Root == nil {
+		return nil, fmt.Errorf("AST JSON has no root node")
+	}
+
+	seen := make(map[int64]bool)
+	var largest int64
+	var check func(node *ASTNode, path string) error
+	check = func(node *ASTNode, path string) error {
+		if node == nil {
+			return fmt.Errorf("%s: missing node", path)
+		}
+		if node.ID <= 0 {
+			return fmt.Errorf("%s: node ID %d is not positive", path, node.ID)
+		}
+		if seen[node.ID] {
+			return fmt.Errorf("%s: duplicate node ID %d", path, node.ID)
+		}
+		if node.Type == "" {
+			return fmt.Errorf("%s: node %d has no type", path, node.ID)
+		}
+		seen[node.ID] = true
+		largest = max(largest, node.ID)
+		for i, child := range node.Children {
+			if err := check(child, fmt.Sprintf("%s.children[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check(document.Root, "root"); err != nil {
+		return nil, err
+	}
+	if _, err := ValidateTyped(document.Root); err != nil {
+		return nil, fmt.Errorf("invalid AST shape: %v", err)
+	}
+
+	for {
+		current := atomic.LoadInt64(&nodeCounter)
+		if current >= largest || atomic.CompareAndSwapInt64(&nodeCounter, current, largest) {
+			break
+		}
+	}
+	return document.Root, nil
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestASTJSONRoundTrip(t *testing.T) {
+	root, err := Validate(`glob { x }
+proc { }
+func { }
+main {
+	var { }
+	x = (x plus 1);
+	print "done"
+}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	data, err := MarshalAST(root)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	decoded, err := UnmarshalAST(data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(root, decoded) {
+		t.Errorf("decoded tree differs from the original")
+	}
+	if !strings.Contains(string(data), `"schema": "spl-ast/1"`) {
+		t.Errorf("missing schema in %s", data)
+	}
+	if id := NewNode("VAR", "y").ID; id <= root.ID {
+		t.Errorf("new node ID %d clashes with decoded IDs up to %d", id, root.ID)
+	}
+}
+
+func TestUnmarshalASTErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"Testing the schema", `{"schema": "spl-ast/0", "root": {"id": 1, "type": "VAR"}}`, "unsupported AST schema"},
+		{"Testing a missing root", `{"schema": "spl-ast/1"}`, "no root"},
+		{"Testing unknown fields", `{"schema": "spl-ast/1", "root": {"id": 1, "type": "VAR", "kind": "x"}}`, "unknown field"},
+		{"Testing duplicate IDs", `{"schema": "spl-ast/1", "root": {"id": 1, "type": "VARIABLES", "children": [{"id": 1, "type": "VAR"}]}}`, "root.children[0]: duplicate node ID 1"},
+		{"Testing missing types", `{"schema": "spl-ast/1", "root": {"id": 1}}`, "has no type"},
+		{"Testing null children", `{"schema": "spl-ast/1", "root": {"id": 1, "type": "VARIABLES", "children": [null]}}`, "missing node"},
+		{"Testing a root that is not a program", `{"schema": "spl-ast/1", "root": {"id": 1, "type": "VAR", "name": "x"}}`, "invalid AST shape: node 1: expected SPL_PROG"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalAST([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, expected one containing %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -22,21 +22,37 @@ func nextID() int64 {
 
 // Base AST ASTNode
 type ASTNode struct {
-	ID       int64
-	Type     string
-	Name     string
-	Children []*ASTNode
+	ID       int64      `json:"id"`
+	Type     string     `json:"type"`
+	Name     string     `json:"name,omitempty"`
+	Children []*ASTNode `json:"children,omitempty"`
+	Span     Span       `json:"span"`
 }
 
+// NewNode creates a node spanning its children. Rules starting or ending
+// with a token widen the span with At.
 func NewNode(nodeType, name string, children ...*ASTNode) *ASTNode {
+	span := Span{}
+	for _, child := range children {
+		span = join(span, child.Span)
+	}
 	return &ASTNode{
 		ID:       nextID(),
 		Type:     nodeType,
 		Name:     name,
 		Children: children,
+		Span:     span,
 	}
 }
 
+// Append adds a child to a list node, widening its span, and returns the
+// node. Lists are built left to right so that long programs do not nest.
+func (n *ASTNode) Append(child *ASTNode) *ASTNode {
+	n.Children = append(n.Children, child)
+	n.Span = join(n.Span, child.Span)
+	return n
+}
+
 func PrintAST(node *ASTNode, indent int) {
 	if node == nil {
 		return
@@ -48,11 +64,26 @@ func PrintAST(node *ASTNode, indent int) {
 	}
 }
 
-//line spl.y:46
+//line spl.y:62
 type yySymType struct {
-	yys  int
-	Str  string
-	node *ASTNode
+	yys     int
+	Str     string
+	Pos     Span
+	program *Program
+	decls   []*VarDecl
+	ident   *Ident
+	proc    *ProcDef
+	procs   []*ProcDef
+	fn      *FuncDef
+	fns     []*FuncDef
+	body    definitionBody
+	main    *Main
+	block   block
+	stmt    Stmt
+	output  Node
+	expr    Expr
+	atom    Atom
+	atoms   []Atom
 }
 
 const GLOB = 57346
@@ -134,7 +165,7 @@ const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line spl.y:212
+//line spl.y:266
 
 func Parse(lex yyLexer) (*ASTNode, error) {
 	if yyParse(lex) != 0 {
@@ -154,64 +185,63 @@ var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
-	-1, 58,
+	-1, 56,
 	19, 5,
 	-2, 4,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 158
+const yyLast = 159
 
 var yyAct = [...]uint8{
-	70, 50, 113, 51, 5, 83, 5, 4, 6, 71,
-	74, 20, 6, 8, 6, 71, 69, 75, 21, 14,
-	73, 130, 24, 121, 54, 29, 52, 53, 60, 61,
-	117, 62, 19, 21, 111, 13, 96, 13, 76, 21,
-	95, 128, 25, 90, 91, 79, 59, 27, 5, 58,
-	41, 27, 93, 6, 71, 63, 103, 104, 105, 106,
-	107, 108, 109, 110, 46, 36, 21, 59, 82, 31,
-	76, 68, 45, 78, 37, 30, 76, 81, 59, 92,
-	59, 94, 80, 15, 86, 6, 71, 89, 59, 100,
-	84, 58, 71, 7, 59, 112, 87, 59, 85, 101,
-	114, 77, 98, 65, 47, 116, 44, 39, 38, 28,
-	22, 10, 3, 119, 124, 123, 115, 84, 118, 97,
-	40, 23, 99, 59, 72, 127, 114, 32, 17, 59,
-	129, 125, 122, 66, 126, 120, 11, 64, 43, 49,
-	34, 35, 18, 9, 2, 102, 88, 67, 57, 16,
-	56, 55, 42, 48, 33, 26, 12, 1,
+	68, 48, 49, 81, 19, 6, 4, 73, 72, 7,
+	69, 67, 52, 50, 51, 58, 59, 20, 60, 7,
+	69, 23, 7, 13, 28, 64, 61, 74, 24, 128,
+	18, 20, 88, 89, 26, 11, 56, 20, 7, 71,
+	14, 40, 7, 69, 93, 57, 6, 14, 5, 45,
+	100, 101, 102, 103, 104, 105, 106, 107, 74, 66,
+	7, 34, 57, 77, 20, 57, 74, 80, 79, 76,
+	78, 64, 92, 7, 69, 118, 57, 90, 82, 44,
+	84, 56, 69, 87, 83, 64, 57, 97, 41, 114,
+	95, 35, 57, 109, 57, 98, 111, 64, 126, 91,
+	113, 108, 85, 112, 82, 75, 63, 43, 116, 42,
+	36, 57, 33, 119, 27, 17, 9, 3, 122, 121,
+	115, 94, 39, 22, 96, 70, 123, 57, 127, 30,
+	16, 125, 120, 64, 124, 117, 62, 38, 47, 32,
+	29, 15, 8, 2, 1, 65, 55, 54, 53, 110,
+	37, 46, 31, 25, 21, 12, 10, 99, 86,
 }
 
 var yyPact = [...]int16{
-	140, -1000, 91, -22, 71, -22, -1000, 138, -1000, 90,
-	-15, 61, -15, 109, -1000, 136, -1000, -22, 89, 101,
-	-1000, -22, -15, 88, -22, 53, -15, 108, 132, -1000,
-	134, -1000, -22, 52, 87, 86, 100, -1000, -22, 129,
-	85, 50, 42, 83, 131, 15, -1000, -22, 127, 82,
-	-1000, 115, -1000, -20, 105, -1000, -1000, -1000, -1000, -3,
-	51, 80, 51, 23, -26, -22, 15, -1000, -1000, -1000,
-	-1000, -1000, -26, 57, 75, -1000, 19, 15, 31, 15,
-	18, 14, -1000, 99, -26, 103, -1000, 15, 51, 30,
-	-1000, -1000, 12, 15, -1000, -1000, 15, -1000, -26, -26,
-	8, 98, 51, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, 120, 1, -1000, 114, -1000, 95, -1000, -1000, 94,
-	51, 117, 15, -1000, -1000, -1000, 20, -1000, 15, -1,
-	-1000,
+	139, -1000, 96, -1000, 26, 137, -1000, -1000, 95, -1000,
+	13, 135, -1000, 111, -1000, 94, -12, -1000, 103, -1000,
+	-12, 6, 93, -12, 133, -1000, 110, 131, -1000, 91,
+	-12, 69, 89, 128, 102, -1000, -12, 66, 88, 86,
+	57, -1000, -1000, 130, 2, 4, 126, 85, 115, -1000,
+	-1000, -25, 106, -1000, -1000, -1000, -1000, 16, 39, 84,
+	39, 2, -15, -12, 2, -1000, -1000, -1000, -1000, -1000,
+	-15, 47, 81, -1000, 8, 2, 78, 115, 50, 22,
+	-1000, 101, -15, 105, -1000, 2, 39, 24, -1000, -1000,
+	79, 2, -1000, 2, -1000, -15, -15, 67, 100, 39,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 120, 53,
+	2, 114, -1000, 99, -1000, -1000, 98, 39, 117, 113,
+	-1000, -1000, -1000, -1000, 77, -1000, 2, 7, -1000,
 }
 
 var yyPgo = [...]uint8{
-	0, 157, 7, 0, 24, 136, 156, 42, 155, 154,
-	153, 2, 32, 11, 152, 17, 1, 3, 151, 150,
-	148, 147, 5, 10, 146, 145,
+	0, 6, 4, 30, 0, 158, 157, 12, 156, 155,
+	154, 153, 152, 151, 150, 1, 149, 2, 148, 147,
+	146, 145, 8, 7, 3, 144,
 }
 
 var yyR1 = [...]int8{
-	0, 1, 2, 2, 3, 4, 5, 5, 6, 7,
-	7, 8, 9, 10, 12, 13, 13, 13, 13, 14,
-	15, 15, 16, 16, 11, 11, 17, 17, 17, 17,
+	0, 25, 1, 1, 4, 7, 8, 8, 9, 10,
+	10, 11, 12, 13, 3, 2, 2, 2, 2, 14,
+	23, 23, 15, 15, 16, 16, 17, 17, 17, 17,
 	17, 17, 18, 18, 19, 19, 20, 20, 21, 21,
-	22, 22, 22, 22, 23, 23, 23, 24, 24, 25,
-	25, 25, 25, 25, 25, 25, 25,
+	24, 24, 24, 24, 22, 22, 22, 5, 5, 6,
+	6, 6, 6, 6, 6, 6, 6,
 }
 
 var yyR2 = [...]int8{
@@ -224,37 +254,35 @@ var yyR2 = [...]int8{
 }
 
 var yyChk = [...]int16{
-	-1000, -1, 4, 21, -2, -3, 34, 22, -2, 5,
-	21, -5, -6, -4, 34, 22, -5, 19, 6, -12,
-	-13, -3, 21, 20, -3, -7, -8, -4, 21, -3,
-	22, -7, 19, -9, 8, 7, -12, 22, 21, 21,
-	20, -13, -14, 9, 21, 22, 22, 21, -10, 8,
-	-16, -17, 11, 12, -4, -18, -19, -20, 34, -3,
-	13, 14, 16, -2, 10, 21, 18, -21, -15, 36,
-	-3, 35, 19, 23, -23, -15, 19, 21, -23, 22,
-	-15, -13, -16, -22, -15, -4, -23, 21, -24, -23,
-	24, 25, -16, 21, -16, 22, 22, 20, -15, 19,
-	-16, -23, -25, 26, 27, 28, 29, 30, 31, 32,
-	33, 22, -16, -11, -17, -15, -22, 22, 20, -23,
-	15, 22, 18, 20, 20, -23, 17, -11, 21, -16,
-	22,
+	-1000, -25, 4, 21, -1, 22, -4, 34, 5, 21,
+	-8, 22, -9, -7, 34, 6, 19, 21, -3, -2,
+	-4, -10, 20, -4, 22, -11, -7, 21, -4, 7,
+	19, -12, 8, 21, -3, 22, 21, -14, 9, 20,
+	-2, 22, 21, 21, 22, -1, -13, 8, -15, -17,
+	11, 12, -7, -18, -19, -20, 34, -4, 13, 14,
+	16, 22, 10, 21, 18, -21, -23, 36, -4, 35,
+	19, 23, -22, -23, 19, 21, -22, -15, -23, -2,
+	-17, -24, -23, -7, -22, 21, -5, -22, 24, 25,
+	-15, 21, 22, 22, 20, -23, 19, -15, -22, -6,
+	26, 27, 28, 29, 30, 31, 32, 33, 22, -15,
+	-16, -17, -23, -24, 22, 20, -22, 15, 22, -17,
+	18, 20, 20, -22, 17, 18, 21, -15, 22,
 }
 
 var yyDef = [...]int8{
-	0, -2, 0, 2, 0, 2, 4, 0, 3, 0,
-	6, 0, 6, 0, 5, 0, 7, 15, 0, 0,
-	14, 16, 9, 0, 17, 0, 9, 0, 0, 18,
-	0, 10, 15, 0, 0, 0, 0, 8, 15, 0,
-	0, 0, 0, 0, 0, 0, 1, 2, 0, 0,
-	12, 22, 26, 0, 0, 29, 30, 31, -2, 0,
-	0, 0, 0, 0, 0, 15, 0, 27, 38, 39,
-	20, 21, 40, 0, 0, 44, 0, 0, 0, 0,
-	0, 0, 23, 0, 41, 0, 33, 0, 0, 0,
-	47, 48, 0, 0, 19, 11, 0, 28, 42, 40,
-	0, 0, 0, 49, 50, 51, 52, 53, 54, 55,
-	56, 0, 0, 13, 0, 43, 0, 34, 45, 0,
-	0, 36, 24, 32, 46, 35, 0, 25, 0, 0,
-	37,
+	0, -2, 0, 2, 0, 0, 3, 4, 0, 6,
+	0, 0, 7, 0, 5, 0, 15, 9, 0, 14,
+	16, 0, 0, 17, 0, 10, 0, 0, 18, 0,
+	15, 0, 0, 0, 0, 8, 15, 0, 0, 0,
+	0, 1, 2, 0, 0, 0, 0, 0, 12, 22,
+	26, 0, 0, 29, 30, 31, -2, 0, 0, 0,
+	0, 0, 0, 15, 0, 27, 38, 39, 20, 21,
+	40, 0, 0, 44, 0, 0, 0, 19, 0, 0,
+	23, 0, 41, 0, 33, 0, 0, 0, 47, 48,
+	0, 0, 11, 0, 28, 42, 40, 0, 0, 0,
+	49, 50, 51, 52, 53, 54, 55, 56, 0, 0,
+	13, 0, 43, 0, 34, 45, 0, 0, 36, 0,
+	24, 32, 46, 35, 0, 25, 0, 0, 37,
 }
 
 var yyTok1 = [...]int8{
@@ -611,341 +639,283 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-16 : yypt+1]
-//line spl.y:79
+//line spl.y:124
 		{
-			yyVAL.node = NewNode("SPL_PROG", "", yyDollar[3].node, yyDollar[7].node, yyDollar[11].node, yyDollar[15].node)
-			ResultAST = yyVAL.node
+			sections := [4]Span{
+				join(yyDollar[1].Pos, yyDollar[4].Pos),
+				join(yyDollar[5].Pos, yyDollar[8].Pos),
+				join(yyDollar[9].Pos, yyDollar[12].Pos),
+				join(yyDollar[13].Pos, yyDollar[16].Pos),
+			}
+			yyVAL.program = newProgram(yyDollar[3].decls, yyDollar[7].procs, yyDollar[11].fns, yyDollar[15].main, sections, join(yyDollar[1].Pos, yyDollar[16].Pos))
+			ResultAST = yyVAL.program.Generic()
+			yylex.(*LexerAdapter).Program = yyVAL.program
 			yylex.(*LexerAdapter).AST = ResultAST
 		}
 	case 2:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line spl.y:87
+//line spl.y:139
 		{
-			yyVAL.node = NewNode("VARIABLES", "empty")
+			yyVAL.decls = nil
 		}
 	case 3:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line spl.y:88
-		{
-			yyVAL.node = NewNode("VARIABLES", "", yyDollar[1].node, yyDollar[2].node)
-		}
-	case 4:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:91
+//line spl.y:140
 		{
-			yyVAL.node = NewNode("VAR", yyDollar[1].Str)
+			yyVAL.decls = append(yyDollar[1].decls, newVarDecl(yyDollar[2].Str, yyDollar[2].Pos))
 		}
 	case 5:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:93
+//line spl.y:146
 		{
-			yyVAL.node = NewNode("NAME", yyDollar[1].Str)
+			yyVAL.ident = newIdent(yyDollar[1].Str, yyDollar[1].Pos)
 		}
 	case 6:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line spl.y:96
+//line spl.y:149
 		{
-			yyVAL.node = NewNode("PROCDEFS", "empty")
+			yyVAL.procs = nil
 		}
 	case 7:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line spl.y:97
+//line spl.y:150
 		{
-			yyVAL.node = NewNode("PROCDEFS", "", yyDollar[1].node, yyDollar[2].node)
+			yyVAL.procs = append(yyDollar[1].procs, yyDollar[2].proc)
 		}
 	case 8:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line spl.y:102
+//line spl.y:155
 		{
-			yyVAL.node = NewNode("PDEF", "", yyDollar[1].node, yyDollar[3].node, yyDollar[6].node)
+			yyVAL.proc = newProcDef(yyDollar[1].ident, yyDollar[3].decls, yyDollar[6].body, yyDollar[7].Pos)
 		}
 	case 9:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line spl.y:106
+//line spl.y:159
 		{
-			yyVAL.node = NewNode("FUNCDEFS", "empty")
+			yyVAL.fns = nil
 		}
 	case 10:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line spl.y:107
+//line spl.y:160
 		{
-			yyVAL.node = NewNode("FUNCDEFS", "", yyDollar[1].node, yyDollar[2].node)
+			yyVAL.fns = append(yyDollar[1].fns, yyDollar[2].fn)
 		}
 	case 11:
 		yyDollar = yyS[yypt-9 : yypt+1]
-//line spl.y:112
+//line spl.y:165
 		{
-			yyVAL.node = NewNode("FDEF", "", yyDollar[1].node, yyDollar[3].node, yyDollar[6].node, yyDollar[8].node)
+			yyVAL.fn = newFuncDef(yyDollar[1].ident, yyDollar[3].decls, yyDollar[6].body, yyDollar[8].atom, yyDollar[9].Pos)
 		}
 	case 12:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line spl.y:117
+//line spl.y:170
 		{
-			yyVAL.node = NewNode("BODY", "", yyDollar[3].node, yyDollar[5].node)
+			yyVAL.body = definitionBody{yyDollar[3].decls, join(yyDollar[1].Pos, yyDollar[4].Pos), yyDollar[5].block, yyDollar[1].Pos}
 		}
 	case 13:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line spl.y:122
+//line spl.y:175
 		{
-			yyVAL.node = NewNode("BODY", "", yyDollar[3].node, yyDollar[5].node)
+			yyVAL.body = definitionBody{yyDollar[3].decls, join(yyDollar[1].Pos, yyDollar[4].Pos), yyDollar[5].block, yyDollar[1].Pos}
 		}
 	case 14:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:125
+//line spl.y:178
 		{
-			yyVAL.node = NewNode("PARAM", "", yyDollar[1].node)
+			yyVAL.decls = yyDollar[1].decls
 		}
 	case 15:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line spl.y:128
+//line spl.y:181
 		{
-			yyVAL.node = NewNode("MAXTHREE", "empty")
+			yyVAL.decls = nil
 		}
 	case 16:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:129
+//line spl.y:182
 		{
-			yyVAL.node = NewNode("MAXTHREE", "", yyDollar[1].node)
+			yyVAL.decls = []*VarDecl{newVarDecl(yyDollar[1].Str, yyDollar[1].Pos)}
 		}
 	case 17:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line spl.y:130
+//line spl.y:183
 		{
-			yyVAL.node = NewNode("MAXTHREE", "", yyDollar[1].node, yyDollar[2].node)
+			yyVAL.decls = []*VarDecl{newVarDecl(yyDollar[1].Str, yyDollar[1].Pos), newVarDecl(yyDollar[2].Str, yyDollar[2].Pos)}
 		}
 	case 18:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line spl.y:131
+//line spl.y:184
 		{
-			yyVAL.node = NewNode("MAXTHREE", "", yyDollar[1].node, yyDollar[2].node, yyDollar[3].node)
+			yyVAL.decls = []*VarDecl{newVarDecl(yyDollar[1].Str, yyDollar[1].Pos), newVarDecl(yyDollar[2].Str, yyDollar[2].Pos), newVarDecl(yyDollar[3].Str, yyDollar[3].Pos)}
 		}
 	case 19:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line spl.y:136
+//line spl.y:189
 		{
-			yyVAL.node = NewNode("MAINPROG", "", yyDollar[3].node, yyDollar[5].node)
+			yyVAL.main = newMain(yyDollar[3].decls, join(yyDollar[1].Pos, yyDollar[4].Pos), yyDollar[5].block, yyDollar[1].Pos)
 		}
 	case 20:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:140
+//line spl.y:193
 		{
-			yyVAL.node = NewNode("ATOM", "Var", yyDollar[1].node)
+			yyVAL.atom = newVarRef(yyDollar[1].Str, yyDollar[1].Pos)
 		}
 	case 21:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:141
+//line spl.y:194
 		{
-			yyVAL.node = NewNode("ATOM", yyDollar[1].Str)
+			yyVAL.atom = newNumberLit(yyDollar[1].Str, yyDollar[1].Pos)
 		}
 	case 22:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:145
+//line spl.y:198
 		{
-			yyVAL.node = NewNode("ALGO", "", yyDollar[1].node)
+			yyVAL.block = block{}.add(yyDollar[1].stmt)
 		}
 	case 23:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line spl.y:146
+//line spl.y:199
 		{
-			yyVAL.node = NewNode("ALGO", "", yyDollar[1].node, yyDollar[3].node)
+			yyVAL.block = yyDollar[1].block.add(yyDollar[3].stmt)
 		}
 	case 24:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line spl.y:150
+//line spl.y:203
 		{
-			yyVAL.node = NewNode("ALGO", "", yyDollar[1].node)
+			yyVAL.block = block{[]Stmt{yyDollar[1].stmt}, join(yyDollar[1].stmt.Span(), yyDollar[2].Pos)}
 		}
 	case 25:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line spl.y:151
+//line spl.y:204
 		{
-			yyVAL.node = NewNode("ALGO", "", yyDollar[1].node, yyDollar[3].node)
+			yyVAL.block = yyDollar[1].block.add(yyDollar[2].stmt)
+			yyVAL.block.span = join(yyVAL.block.span, yyDollar[3].Pos)
 		}
 	case 26:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:155
+//line spl.y:208
 		{
-			yyVAL.node = NewNode("INSTR", "halt")
+			yyVAL.stmt = newHalt(yyDollar[1].Pos)
 		}
 	case 27:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line spl.y:156
+//line spl.y:209
 		{
-			yyVAL.node = NewNode("INSTR", "print", yyDollar[2].node)
+			yyVAL.stmt = newPrint(yyDollar[2].output, yyDollar[1].Pos)
 		}
 	case 28:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line spl.y:157
+//line spl.y:210
 		{
-			yyVAL.node = NewNode("INSTR", "call", yyDollar[1].node, yyDollar[3].node)
+			yyVAL.stmt = newCallStmt(yyDollar[1].ident, yyDollar[3].atoms, yyDollar[4].Pos)
 		}
 	case 29:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:158
+//line spl.y:211
 		{
-			yyVAL.node = NewNode("INSTR", "assign", yyDollar[1].node)
+			yyVAL.stmt = yyDollar[1].stmt
 		}
 	case 30:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:159
+//line spl.y:212
 		{
-			yyVAL.node = NewNode("INSTR", "loop", yyDollar[1].node)
+			yyVAL.stmt = yyDollar[1].stmt
 		}
 	case 31:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:160
+//line spl.y:213
 		{
-			yyVAL.node = NewNode("INSTR", "branch", yyDollar[1].node)
+			yyVAL.stmt = yyDollar[1].stmt
 		}
 	case 32:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line spl.y:164
+//line spl.y:217
 		{
-			yyVAL.node = NewNode("ASSIGN", "call", yyDollar[1].node, yyDollar[3].node, yyDollar[5].node)
+			yyVAL.stmt = newCallAssign(newVarRef(yyDollar[1].Str, yyDollar[1].Pos), yyDollar[3].ident, yyDollar[5].atoms, yyDollar[6].Pos)
 		}
 	case 33:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line spl.y:165
+//line spl.y:218
 		{
-			yyVAL.node = NewNode("ASSIGN", "", yyDollar[1].node, yyDollar[3].node)
+			yyVAL.stmt = newAssign(newVarRef(yyDollar[1].Str, yyDollar[1].Pos), yyDollar[3].expr)
 		}
 	case 34:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line spl.y:169
+//line spl.y:222
 		{
-			yyVAL.node = NewNode("LOOP", "while", yyDollar[2].node, yyDollar[4].node)
+			yyVAL.stmt = newWhile(yyDollar[2].expr, yyDollar[4].block, yyDollar[1].Pos, yyDollar[5].Pos)
 		}
 	case 35:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line spl.y:170
+//line spl.y:223
 		{
-			yyVAL.node = NewNode("LOOP", "do", yyDollar[3].node, yyDollar[6].node)
+			yyVAL.stmt = newDoUntil(yyDollar[3].block, yyDollar[6].expr, yyDollar[1].Pos)
 		}
 	case 36:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line spl.y:174
+//line spl.y:227
 		{
-			yyVAL.node = NewNode("BRANCH", "if", yyDollar[2].node, yyDollar[4].node)
+			yyVAL.stmt = newIf(yyDollar[2].expr, yyDollar[4].block, yyDollar[1].Pos, yyDollar[5].Pos)
 		}
 	case 37:
 		yyDollar = yyS[yypt-9 : yypt+1]
-//line spl.y:175
+//line spl.y:228
 		{
-			yyVAL.node = NewNode("BRANCH", "ifelse", yyDollar[2].node, yyDollar[4].node, yyDollar[8].node)
+			yyVAL.stmt = newIfElse(yyDollar[2].expr, yyDollar[4].block, yyDollar[8].block, yyDollar[1].Pos, yyDollar[9].Pos)
 		}
 	case 38:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:179
+//line spl.y:232
 		{
-			yyVAL.node = NewNode("OUTPUT", "atom", yyDollar[1].node)
+			yyVAL.output = yyDollar[1].atom
 		}
 	case 39:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:180
+//line spl.y:233
 		{
-			yyVAL.node = NewNode("OUTPUT", yyDollar[1].Str)
+			yyVAL.output = newStringLit(yyDollar[1].Str, yyDollar[1].Pos)
 		}
 	case 40:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line spl.y:184
+//line spl.y:237
 		{
-			yyVAL.node = NewNode("INPUT", "empty")
+			yyVAL.atoms = nil
 		}
 	case 41:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:185
+//line spl.y:238
 		{
-			yyVAL.node = NewNode("INPUT", "", yyDollar[1].node)
+			yyVAL.atoms = []Atom{yyDollar[1].atom}
 		}
 	case 42:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line spl.y:186
+//line spl.y:239
 		{
-			yyVAL.node = NewNode("INPUT", "", yyDollar[1].node, yyDollar[2].node)
+			yyVAL.atoms = []Atom{yyDollar[1].atom, yyDollar[2].atom}
 		}
 	case 43:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line spl.y:187
+//line spl.y:240
 		{
-			yyVAL.node = NewNode("INPUT", "", yyDollar[1].node, yyDollar[2].node, yyDollar[3].node)
+			yyVAL.atoms = []Atom{yyDollar[1].atom, yyDollar[2].atom, yyDollar[3].atom}
 		}
 	case 44:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:191
+//line spl.y:244
 		{
-			yyVAL.node = NewNode("TERM", "atom", yyDollar[1].node)
+			yyVAL.expr = yyDollar[1].atom
 		}
 	case 45:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line spl.y:192
+//line spl.y:245
 		{
-			yyVAL.node = NewNode("TERM", "unop", yyDollar[2].node, yyDollar[3].node)
+			yyVAL.expr = newUnaryExpr(yyDollar[2].Str, yyDollar[2].Pos, yyDollar[3].expr, yyDollar[1].Pos, yyDollar[4].Pos)
 		}
 	case 46:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line spl.y:193
-		{
-			yyVAL.node = NewNode("TERM", "binop", yyDollar[2].node, yyDollar[3].node, yyDollar[4].node)
-		}
-	case 47:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:197
-		{
-			yyVAL.node = NewNode("UNOP", yyDollar[1].Str)
-		}
-	case 48:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:198
-		{
-			yyVAL.node = NewNode("UNOP", yyDollar[1].Str)
-		}
-	case 49:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:202
-		{
-			yyVAL.node = NewNode("BINOP", yyDollar[1].Str)
-		}
-	case 50:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:203
-		{
-			yyVAL.node = NewNode("BINOP", yyDollar[1].Str)
-		}
-	case 51:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:204
-		{
-			yyVAL.node = NewNode("BINOP", yyDollar[1].Str)
-		}
-	case 52:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:205
-		{
-			yyVAL.node = NewNode("BINOP", yyDollar[1].Str)
-		}
-	case 53:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:206
-		{
-			yyVAL.node = NewNode("BINOP", yyDollar[1].Str)
-		}
-	case 54:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:207
-		{
-			yyVAL.node = NewNode("BINOP", yyDollar[1].Str)
-		}
-	case 55:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:208
-		{
-			yyVAL.node = NewNode("BINOP", yyDollar[1].Str)
-		}
-	case 56:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line spl.y:209
+//line spl.y:246
 		{
-			yyVAL.node = NewNode("BINOP", yyDollar[1].Str)
+			yyVAL.expr = newBinaryExpr(yyDollar[2].expr, yyDollar[3].Str, yyDollar[3].Pos, yyDollar[4].expr, yyDollar[1].Pos, yyDollar[5].Pos)
 		}
 	}
 	goto yystack /* stack new state and value */
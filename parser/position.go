@@ -0,0 +1,87 @@
+package parser
+
+import "fmt"
+
+// Position is a location in the source. Line and Column start at 1, Offset
+// is the byte offset from the start of the input.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// Span is the source range of a node, from its first character up to but
+// not including the character after it.
+//
+// Most nodes span the tokens they were parsed from. Lists with a keyword
+// of their own span the keyword and the braces too, whether they are
+// empty or not:
+//
+//   - VARIABLES, PROCDEFS and FUNCDEFS of a program span from glob, proc
+//     or func to the closing brace of the section;
+//   - the VARIABLES of main spans var { ... };
+//   - the MAXTHREE of a definition body spans local { ... }.
+//
+// That way NodeAt finds the list on its keyword and braces, diagnostics
+// about a list point at all of it, and comments between the keyword and
+// the first element fall inside it. Other empty lists, such as the
+// parameters of a definition without any, have the zero Span.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+func (s Span) IsZero() bool {
+	return s.Start.Line == 0
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%d:%d-%d:%d", s.Start.Line, s.Start.Column, s.End.Line, s.End.Column)
+}
+
+// join returns the smallest span covering both spans, ignoring zero ones.
+func join(a, b Span) Span {
+	switch {
+	case a.IsZero():
+		return b
+	case b.IsZero():
+		return a
+	}
+	if b.Start.Offset < a.Start.Offset {
+		a.Start = b.Start
+	}
+	if b.End.Offset > a.End.Offset {
+		a.End = b.End
+	}
+	return a
+}
+
+// At sets the span of a node to run from the start of first to the end of
+// last and returns the node.
+func (n *ASTNode) At(first, last Span) *ASTNode {
+	n.Span = join(first, last)
+	return n
+}
+
+// Contains reports whether offset lies within the span. The offset just
+// after the span counts as inside, where an editor cursor ends a word.
+func (s Span) Contains(offset int) bool {
+	return !s.IsZero() && s.Start.Offset <= offset && offset <= s.End.Offset
+}
+
+// NodeAt returns the innermost node below root whose span contains offset,
+// or nil if there is none.
+func NodeAt(root *ASTNode, offset int) *ASTNode {
+	var found *ASTNode
+	Inspect(root, func(node *ASTNode) bool {
+		if node.Span.IsZero() {
+			return true
+		}
+		if !node.Span.Contains(offset) {
+			return false
+		}
+		found = node
+		return true
+	})
+	return found
+}
@@ -0,0 +1,57 @@
+package parser
+
+// Clone deep-copies a tree, giving every copy a fresh ID. The map takes the
+// ID of each copy to the ID of the node it was copied from.
+func Clone(node *ASTNode) (*ASTNode, map[int64]int64) {
+	originals := make(map[int64]int64)
+	var clone func(node *ASTNode) *ASTNode
+	clone = func(node *ASTNode) *ASTNode {
+		if node == nil {
+			return nil
+		}
+		copied := &ASTNode{
+			ID:   nextID(),
+			Type: node.Type,
+			Name: node.Name,
+			Span: node.Span,
+		}
+		if node.Children != nil {
+			copied.Children = make([]*ASTNode, len(node.Children))
+			for i, child := range node.Children {
+				copied.Children[i] = clone(child)
+			}
+		}
+		originals[copied.ID] = node.ID
+		return copied
+	}
+	return clone(node), originals
+}
+
+// Replace puts replacement in the place of old below root and reports
+// whether old was found. The root itself cannot be replaced.
+func Replace(root, old, replacement *ASTNode) bool {
+	found := false
+	Inspect(root, func(node *ASTNode) bool {
+		for i, child := range node.Children {
+			if child == old {
+				node.Children[i] = replacement
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// Rewrite calls f on every node of a tree, children first, and puts
+// whatever f returns in the node's place. f returns the node itself to keep
+// it. The rewritten root is returned.
+func Rewrite(node *ASTNode, f func(*ASTNode) *ASTNode) *ASTNode {
+	if node == nil {
+		return nil
+	}
+	for i, child := range node.Children {
+		node.Children[i] = Rewrite(child, f)
+	}
+	return f(node)
+}
@@ -0,0 +1,42 @@
+package parser
+
+import "testing"
+
+func TestClone(t *testing.T) {
+	root := NewNode("TERM", "binop", NewNode("TERM", "atom", NewNode("ATOM", "1")), NewNode("BINOP", "plus"), NewNode("TERM", "atom", NewNode("ATOM", "2")))
+	clone, originals := Clone(root)
+	if FormatAST(clone) == FormatAST(root) {
+		t.Errorf("clone kept the original IDs")
+	}
+	count := 0
+	Walk(clone, nil, func(node *ASTNode) {
+		count++
+		original := GetNodeByID(root, int(originals[node.ID]))
+		if original == nil || original == node || original.Type != node.Type || original.Name != node.Name {
+			t.Errorf("node %d does not map to its original", node.ID)
+		}
+	})
+	if count != len(originals) {
+		t.Errorf("got %d mappings for %d nodes", len(originals), count)
+	}
+
+	three := NewNode("ATOM", "3")
+	if !Replace(clone, clone.Children[2].Children[0], three) || clone.Children[2].Children[0] != three {
+		t.Errorf("replacement not found in the tree")
+	}
+	if Replace(clone, root.Children[0], three) {
+		t.Errorf("replaced a node that is not in the tree")
+	}
+
+	names := ""
+	Rewrite(clone, func(node *ASTNode) *ASTNode {
+		names += node.Name + " "
+		if node.Type == "BINOP" {
+			return NewNode("BINOP", "mult")
+		}
+		return node
+	})
+	if expected := "1 atom plus 3 atom binop "; names != expected || clone.Children[1].Name != "mult" {
+		t.Errorf("got rewrite order %q and operator %s", names, clone.Children[1].Name)
+	}
+}
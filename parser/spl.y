@@ -16,21 +16,37 @@ func nextID() int64 {
 
 // Base AST ASTNode
 type ASTNode struct {
-	ID       int64
-	Type     string
-	Name     string
-	Children []*ASTNode
+	ID       int64      `json:"id"`
+	Type     string     `json:"type"`
+	Name     string     `json:"name,omitempty"`
+	Children []*ASTNode `json:"children,omitempty"`
+	Span     Span       `json:"span"`
 }
 
+// NewNode creates a node spanning its children. Rules starting or ending
+// with a token widen the span with At.
 func NewNode(nodeType, name string, children ...*ASTNode) *ASTNode {
+	span := Span{}
+	for _, child := range children {
+		span = join(span, child.Span)
+	}
 	return &ASTNode{
 		ID:       nextID(),
 		Type:     nodeType,
 		Name:     name,
 		Children: children,
+		Span:     span,
 	}
 }
 
+// Append adds a child to a list node, widening its span, and returns the
+// node. Lists are built left to right so that long programs do not nest.
+func (n *ASTNode) Append(child *ASTNode) *ASTNode {
+	n.Children = append(n.Children, child)
+	n.Span = join(n.Span, child.Span)
+	return n
+}
+
 func PrintAST(node *ASTNode, indent int) {
 	if node == nil {
 		return
@@ -44,8 +60,23 @@ func PrintAST(node *ASTNode, indent int) {
 %}
 
 %union {
-    Str   string
-    node  *ASTNode
+    Str      string
+    Pos      Span
+    program  *Program
+    decls    []*VarDecl
+    ident    *Ident
+    proc     *ProcDef
+    procs    []*ProcDef
+    fn       *FuncDef
+    fns      []*FuncDef
+    body     definitionBody
+    main     *Main
+    block    block
+    stmt     Stmt
+    output   Node
+    expr     Expr
+    atom     Atom
+    atoms    []Atom
 }
 
 %token GLOB PROC FUNC MAIN
@@ -60,14 +91,28 @@ func PrintAST(node *ASTNode, indent int) {
 %token <Str> NEG NOT
 %token <Str> EQ GT OR AND PLUS MINUS MULT DIV
 %token <Str> IDENT NUMBER STRING
-%type <node> spl_prog variables var name procdefs pdef funcdefs fdef body bodyFunc bodyalgo param maxthree mainprog atom algo instr assign loop branch output input term unop binop
+%type <decls> variables maxthree param
+%type <Str> var unop binop
+%type <ident> name
+%type <procs> procdefs
+%type <proc> pdef
+%type <fns> funcdefs
+%type <fn> fdef
+%type <body> body bodyFunc
+%type <main> mainprog
+%type <block> algo bodyalgo
+%type <stmt> instr assign loop branch
+%type <output> output
+%type <expr> term
+%type <atom> atom
+%type <atoms> input
 %left OR AND
 %left PLUS MINUS
 %left MULT DIV
 %right NEG NOT
 
 %start spl_prog
-%type <node> spl_prog
+%type <program> spl_prog
 
 %%
 
@@ -77,136 +122,145 @@ spl_prog
       FUNC LBRACE funcdefs  RBRACE
       MAIN LBRACE mainprog  RBRACE
       {
-        $$ = NewNode("SPL_PROG", "", $3, $7, $11, $15)
-        ResultAST = $$
+        sections := [4]Span{
+          join($<Pos>1, $<Pos>4),
+          join($<Pos>5, $<Pos>8),
+          join($<Pos>9, $<Pos>12),
+          join($<Pos>13, $<Pos>16),
+        }
+        $$ = newProgram($3, $7, $11, $15, sections, join($<Pos>1, $<Pos>16))
+        ResultAST = $$.Generic()
+        yylex.(*LexerAdapter).Program = $$
         yylex.(*LexerAdapter).AST = ResultAST
       }
     ;
 
 variables
-    : /* empty */        { $$ = NewNode("VARIABLES", "empty") }
-    | var variables      { $$ = NewNode("VARIABLES", "", $1, $2) }
+    : /* empty */        { $$ = nil }
+    | variables var      { $$ = append($1, newVarDecl($2, $<Pos>2)) }
     ;
 
-var  : IDENT { $$ = NewNode("VAR", $1) };
+/* var keeps the Str and Pos of its IDENT. */
+var  : IDENT ;
 
-name : IDENT { $$ = NewNode("NAME", $1) };
+name : IDENT { $$ = newIdent($1, $<Pos>1) };
 
 procdefs
-    : /* empty */        { $$ = NewNode("PROCDEFS", "empty") }
-    | pdef procdefs      { $$ = NewNode("PROCDEFS", "", $1, $2) }
+    : /* empty */        { $$ = nil }
+    | procdefs pdef      { $$ = append($1, $2) }
     ;
 
 pdef
     : name LPAREN param RPAREN LBRACE body RBRACE
-      { $$ = NewNode("PDEF", "", $1, $3, $6) }
+      { $$ = newProcDef($1, $3, $6, $<Pos>7) }
     ;
 
 funcdefs
-    : /* empty */        { $$ = NewNode("FUNCDEFS", "empty") }
-    | fdef funcdefs      { $$ = NewNode("FUNCDEFS", "", $1, $2) }
+    : /* empty */        { $$ = nil }
+    | funcdefs fdef      { $$ = append($1, $2) }
     ;
 
 fdef
     : name LPAREN param RPAREN LBRACE bodyFunc RETURN atom RBRACE
-        { $$ = NewNode("FDEF", "", $1, $3, $6, $8) }
+        { $$ = newFuncDef($1, $3, $6, $8, $<Pos>9) }
     ;
 
 body
     : LOCAL LBRACE maxthree RBRACE algo
-        { $$ = NewNode("BODY", "", $3, $5) }
+        { $$ = definitionBody{$3, join($<Pos>1, $<Pos>4), $5, $<Pos>1} }
     ;
 
 bodyFunc
     : LOCAL LBRACE maxthree RBRACE bodyalgo
-        { $$ = NewNode("BODY", "", $3, $5) }
+        { $$ = definitionBody{$3, join($<Pos>1, $<Pos>4), $5, $<Pos>1} }
     ;
 
-param : maxthree { $$ = NewNode("PARAM", "", $1) };
+param : maxthree { $$ = $1 };
 
 maxthree
-    : /* empty */         { $$ = NewNode("MAXTHREE", "empty") }
-    | var                 { $$ = NewNode("MAXTHREE", "", $1) }
-    | var var             { $$ = NewNode("MAXTHREE", "", $1, $2) }
-    | var var var         { $$ = NewNode("MAXTHREE", "", $1, $2, $3) }
+    : /* empty */         { $$ = nil }
+    | var                 { $$ = []*VarDecl{newVarDecl($1, $<Pos>1)} }
+    | var var             { $$ = []*VarDecl{newVarDecl($1, $<Pos>1), newVarDecl($2, $<Pos>2)} }
+    | var var var         { $$ = []*VarDecl{newVarDecl($1, $<Pos>1), newVarDecl($2, $<Pos>2), newVarDecl($3, $<Pos>3)} }
     ;
 
 mainprog
-    : VAR LBRACE variables RBRACE algo 
-        { $$ = NewNode("MAINPROG", "", $3, $5) }
+    : VAR LBRACE variables RBRACE algo
+        { $$ = newMain($3, join($<Pos>1, $<Pos>4), $5, $<Pos>1) }
     ;
 
 atom
-    : var { $$ = NewNode("ATOM", "Var", $1) }
-    | NUMBER { $$ = NewNode("ATOM", $1) }
+    : var { $$ = newVarRef($1, $<Pos>1) }
+    | NUMBER { $$ = newNumberLit($1, $<Pos>1) }
     ;
 
-algo 
-    : instr { $$ = NewNode("ALGO", "", $1) }
-    | instr SEMICOLON algo { $$ = NewNode("ALGO", "", $1, $3) }
+algo
+    : instr { $$ = block{}.add($1) }
+    | algo SEMICOLON instr { $$ = $1.add($3) }
     ;
 
 bodyalgo
-    : instr SEMICOLON { $$ = NewNode("ALGO", "", $1) }
-    | instr SEMICOLON bodyalgo { $$ = NewNode("ALGO", "", $1, $3) }
+    : instr SEMICOLON { $$ = block{[]Stmt{$1}, join($1.Span(), $<Pos>2)} }
+    | bodyalgo instr SEMICOLON { $$ = $1.add($2); $$.span = join($$.span, $<Pos>3) }
     ;
 
 instr
-    : HALT                      { $$ = NewNode("INSTR", "halt") }
-    | PRINT output              { $$ = NewNode("INSTR", "print", $2) }
-    | name LPAREN input RPAREN  { $$ = NewNode("INSTR", "call", $1, $3) }
-    | assign                    { $$ = NewNode("INSTR", "assign", $1) }
-    | loop                      { $$ = NewNode("INSTR", "loop", $1) }
-    | branch                    { $$ = NewNode("INSTR", "branch", $1) }
+    : HALT                      { $$ = newHalt($<Pos>1) }
+    | PRINT output              { $$ = newPrint($2, $<Pos>1) }
+    | name LPAREN input RPAREN  { $$ = newCallStmt($1, $3, $<Pos>4) }
+    | assign                    { $$ = $1 }
+    | loop                      { $$ = $1 }
+    | branch                    { $$ = $1 }
     ;
 
 assign
-    : var ASSIGN name LPAREN input RPAREN { $$ = NewNode("ASSIGN", "call", $1, $3, $5) }
-    | var ASSIGN term                     { $$ = NewNode("ASSIGN", "", $1, $3) }
+    : var ASSIGN name LPAREN input RPAREN { $$ = newCallAssign(newVarRef($1, $<Pos>1), $3, $5, $<Pos>6) }
+    | var ASSIGN term                     { $$ = newAssign(newVarRef($1, $<Pos>1), $3) }
     ;
 
 loop
-    : WHILE term LBRACE algo RBRACE          { $$ = NewNode("LOOP", "while", $2, $4) }
-    | DO LBRACE algo RBRACE UNTIL term       { $$ = NewNode("LOOP", "do", $3, $6) }
+    : WHILE term LBRACE algo RBRACE          { $$ = newWhile($2, $4, $<Pos>1, $<Pos>5) }
+    | DO LBRACE algo RBRACE UNTIL term       { $$ = newDoUntil($3, $6, $<Pos>1) }
     ;
 
 branch
-    : IF term LBRACE algo RBRACE                           { $$ = NewNode("BRANCH", "if", $2, $4) }
-    | IF term LBRACE algo RBRACE ELSE LBRACE algo RBRACE   { $$ = NewNode("BRANCH", "ifelse", $2, $4, $8) }
+    : IF term LBRACE algo RBRACE                           { $$ = newIf($2, $4, $<Pos>1, $<Pos>5) }
+    | IF term LBRACE algo RBRACE ELSE LBRACE algo RBRACE   { $$ = newIfElse($2, $4, $8, $<Pos>1, $<Pos>9) }
     ;
 
 output
-    : atom   { $$ = NewNode("OUTPUT", "atom", $1) }
-    | STRING { $$ = NewNode("OUTPUT", $1) }
+    : atom   { $$ = $1 }
+    | STRING { $$ = newStringLit($1, $<Pos>1) }
     ;
 
 input
-    : /* empty */     { $$ = NewNode("INPUT", "empty") }
-    | atom            { $$ = NewNode("INPUT", "", $1) }
-    | atom atom       { $$ = NewNode("INPUT", "", $1, $2) }
-    | atom atom atom  { $$ = NewNode("INPUT", "", $1, $2, $3) }
+    : /* empty */     { $$ = nil }
+    | atom            { $$ = []Atom{$1} }
+    | atom atom       { $$ = []Atom{$1, $2} }
+    | atom atom atom  { $$ = []Atom{$1, $2, $3} }
     ;
 
 term
-    : atom                           { $$ = NewNode("TERM", "atom", $1) }
-    | LPAREN unop term RPAREN        { $$ = NewNode("TERM", "unop", $2, $3) }
-    | LPAREN term binop term RPAREN  { $$ = NewNode("TERM", "binop", $2, $3, $4) }
+    : atom                           { $$ = $1 }
+    | LPAREN unop term RPAREN        { $$ = newUnaryExpr($2, $<Pos>2, $3, $<Pos>1, $<Pos>4) }
+    | LPAREN term binop term RPAREN  { $$ = newBinaryExpr($2, $3, $<Pos>3, $4, $<Pos>1, $<Pos>5) }
     ;
 
+/* unop and binop keep the Str and Pos of their token. */
 unop
-    : NEG { $$ = NewNode("UNOP", $1) }
-    | NOT { $$ = NewNode("UNOP", $1) }
+    : NEG
+    | NOT
     ;
 
 binop
-    : EQ      { $$ = NewNode("BINOP", $1) }
-    | GT      { $$ = NewNode("BINOP", $1) }
-    | OR      { $$ = NewNode("BINOP", $1) }
-    | AND     { $$ = NewNode("BINOP", $1) }
-    | PLUS    { $$ = NewNode("BINOP", $1) }
-    | MINUS   { $$ = NewNode("BINOP", $1) }
-    | MULT    { $$ = NewNode("BINOP", $1) }
-    | DIV     { $$ = NewNode("BINOP", $1) }
+    : EQ
+    | GT
+    | OR
+    | AND
+    | PLUS
+    | MINUS
+    | MULT
+    | DIV
     ;
 
 %%
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlatLists(t *testing.T) {
+	const count = 5000
+	assignments := make([]string, count)
+	for i := range assignments {
+		assignments[i] = "x = 1"
+	}
+	root, err := Validate(`glob { x y z }
+proc { p() { local { } halt } q() { local { } halt } }
+func { }
+main {
+	var { }
+	` + strings.Join(assignments, ";\n\t") + `
+}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		list     *ASTNode
+		nodeType string
+		length   int
+	}{
+		{"Testing globals", root.Children[0], "VAR", 3},
+		{"Testing procedures", root.Children[1], "PDEF", 2},
+		{"Testing functions", root.Children[2], "FDEF", 0},
+		{"Testing main variables", root.Children[3].Children[0], "VAR", 0},
+		{"Testing instructions", root.Children[3].Children[1], "INSTR", count},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.list.Children) != tt.length {
+				t.Fatalf("got %d children, expected %d", len(tt.list.Children), tt.length)
+			}
+			for _, child := range tt.list.Children {
+				if child.Type != tt.nodeType {
+					t.Fatalf("got a %s child, expected %s", child.Type, tt.nodeType)
+				}
+			}
+		})
+	}
+
+	if span := root.Children[0].Span; span.Start.Column != 1 || span.End.Column != 15 {
+		t.Errorf("globals span %s, expected 1:1-1:15", span)
+	}
+}
+
+// TestListSpans checks the span contract documented on Span for the lists
+// that have a keyword of their own.
+func TestListSpans(t *testing.T) {
+	input := `glob { g }
+proc {
+	p(a) { local { } halt }
+}
+func { }
+main {
+	var { x y }
+	halt
+}`
+	root, err := Validate(input)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	tests := []struct {
+		name     string
+		node     *ASTNode
+		expected string
+	}{
+		{"Testing the glob section", root.Children[0], "glob { g }"},
+		{"Testing the proc section", root.Children[1], "proc {\n\tp(a) { local { } halt }\n}"},
+		{"Testing an empty func section", root.Children[2], "func { }"},
+		{"Testing the var declarations", root.Children[3].Children[0], "var { x y }"},
+		{"Testing empty local declarations", root.Children[1].Children[0].Children[2].Children[0], "local { }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := input[tt.node.Span.Start.Offset:tt.node.Span.End.Offset]; got != tt.expected {
+				t.Errorf("spans %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package parser
+
+import "fmt"
+
+// The typed AST gives every construct of the language a struct of its own,
+// with lists as slices and children as named fields. It is a view over the
+// generic ASTNode tree the passes work on: every typed node keeps the node
+// it was built from, so IDs, symbol table entries and spans stay shared.
+//
+// The parser builds the typed nodes, and the generic tree is derived from
+// them: each grammar action makes its typed node together with the generic
+// node it views. ParseProgram returns the typed AST of a source. Typed reads
+// one off a generic tree that did not come from the parser, such as a
+// decoded or rewritten one.
+
+// Node is implemented by every typed node.
+type Node interface {
+	// Generic returns the ASTNode the typed node views.
+	Generic() *ASTNode
+	Span() Span
+}
+
+// Stmt is an instruction. It views an INSTR node.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Expr is a term. Binary and unary expressions view TERM nodes, atoms view
+// their VAR or ATOM node.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Atom is a variable or number, the only operands of calls, returns and
+// print.
+type Atom interface {
+	Expr
+	atomNode()
+}
+
+type view struct {
+	node *ASTNode
+}
+
+func (v view) Generic() *ASTNode { return v.node }
+func (v view) Span() Span        { return v.node.Span }
+
+// Program views SPL_PROG.
+type Program struct {
+	view
+	Globals []*VarDecl
+	Procs   []*ProcDef
+	Funcs   []*FuncDef
+	Main    *Main
+}
+
+// VarDecl is a declared global, local, parameter or main variable. It
+// views a VAR node.
+type VarDecl struct {
+	view
+	Name string
+}
+
+// Ident is the name of a procedure or function, at its definition or a
+// call. It views a NAME node.
+type Ident struct {
+	view
+	Name string
+}
+
+// ProcDef views PDEF.
+type ProcDef struct {
+	view
+	Name   *Ident
+	Params []*VarDecl
+	Locals []*VarDecl
+	Body   []Stmt
+}
+
+// FuncDef views FDEF.
+type FuncDef struct {
+	view
+	Name   *Ident
+	Params []*VarDecl
+	Locals []*VarDecl
+	Body   []Stmt
+	Return Atom
+}
+
+// Main views MAINPROG.
+type Main struct {
+	view
+	Vars []*VarDecl
+	Body []Stmt
+}
+
+// Halt is the halt instruction.
+type Halt struct{ view }
+
+// Print prints Value, an Atom or a *StringLit.
+type Print struct {
+	view
+	Value Node
+}
+
+// CallStmt calls a procedure.
+type CallStmt struct {
+	view
+	Name *Ident
+	Args []Atom
+}
+
+// Assign assigns a term to a variable.
+type Assign struct {
+	view
+	Target *VarRef
+	Value  Expr
+}
+
+// CallAssign assigns the result of a function call to a variable.
+type CallAssign struct {
+	view
+	Target *VarRef
+	Name   *Ident
+	Args   []Atom
+}
+
+// While loops while Cond holds.
+type While struct {
+	view
+	Cond Expr
+	Body []Stmt
+}
+
+// DoUntil runs Body until Cond holds.
+type DoUntil struct {
+	view
+	Body []Stmt
+	Cond Expr
+}
+
+// If runs Then when Cond holds and otherwise Else, which is nil for a
+// branch without an else part.
+type If struct {
+	view
+	Cond Expr
+	Then []Stmt
+	Else []Stmt
+}
+
+// UnaryExpr applies neg or not.
+type UnaryExpr struct {
+	view
+	Op      string
+	Operand Expr
+}
+
+// BinaryExpr applies eq, >, or, and, plus, minus, mult or div.
+type BinaryExpr struct {
+	view
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// VarRef is a use of a variable. It views a VAR node.
+type VarRef struct {
+	view
+	Name string
+}
+
+// NumberLit is a number, kept as written. It views an ATOM node.
+type NumberLit struct {
+	view
+	Value string
+}
+
+// StringLit is a string without its quotes. It views an OUTPUT node.
+type StringLit struct {
+	view
+	Value string
+}
+
+func (*Halt) stmtNode()       {}
+func (*Print) stmtNode()      {}
+func (*CallStmt) stmtNode()   {}
+func (*Assign) stmtNode()     {}
+func (*CallAssign) stmtNode() {}
+func (*While) stmtNode()      {}
+func (*DoUntil) stmtNode()    {}
+func (*If) stmtNode()         {}
+
+func (*UnaryExpr) exprNode()  {}
+func (*BinaryExpr) exprNode() {}
+func (*VarRef) exprNode()     {}
+func (*NumberLit) exprNode()  {}
+
+func (*VarRef) atomNode()    {}
+func (*NumberLit) atomNode() {}
+
+func ValidateTyped(root *ASTNode) (program *Program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return Typed(root), nil
+}
+
+// Typed builds the typed view of a generic tree, panicking when the tree
+// does not have the shape the parser produces. It depends on the generic
+// tree's conventions, such as a print of a string being an OUTPUT node
+// without children that holds the string as its name.
+func Typed(root *ASTNode) *Program {
+	expect(root, "SPL_PROG", 4)
+	program := &Program{
+		view:    view{root},
+		Globals: typedVariables(root.Children[0]),
+		Procs:   make([]*ProcDef, 0),
+		Funcs:   make([]*FuncDef, 0),
+	}
+	for _, def := range list(root.Children[1], "PROCDEFS") {
+		expect(def, "PDEF", 3)
+		params, locals, body := typedDefinition(def)
+		program.Procs = append(program.Procs, &ProcDef{view{def}, typedName(def.Children[0]), params, locals, body})
+	}
+	for _, def := range list(root.Children[2], "FUNCDEFS") {
+		expect(def, "FDEF", 4)
+		params, locals, body := typedDefinition(def)
+		program.Funcs = append(program.Funcs, &FuncDef{view{def}, typedName(def.Children[0]), params, locals, body, typedAtom(def.Children[3])})
+	}
+	main := root.Children[3]
+	expect(main, "MAINPROG", 2)
+	program.Main = &Main{view{main}, typedVariables(main.Children[0]), typedAlgo(main.Children[1])}
+	return program
+}
+
+func expect(node *ASTNode, nodeType string, children int) {
+	if node == nil {
+		panic(fmt.Sprintf("expected %s, found nothing", nodeType))
+	}
+	if node.Type != nodeType || len(node.Children) != children {
+		panic(fmt.Sprintf("node %d: expected %s with %d children, found %s with %d", node.ID, nodeType, children, node.Type, len(node.Children)))
+	}
+}
+
+// expectName panics unless node has one of the names the parser gives its
+// type.
+func expectName(node *ASTNode, kind string, names ...string) {
+	for _, name := range names {
+		if node.Name == name {
+			return
+		}
+	}
+	panic(fmt.Sprintf("node %d: unknown %s %q", node.ID, kind, node.Name))
+}
+
+// list returns the children of a list node of the given type.
+func list(node *ASTNode, nodeType string) []*ASTNode {
+	if node == nil || node.Type != nodeType {
+		panic(fmt.Sprintf("expected %s list", nodeType))
+	}
+	return node.Children
+}
+
+func typedVariables(node *ASTNode) []*VarDecl {
+	output := make([]*VarDecl, 0)
+	for _, v := range list(node, "VARIABLES") {
+		output = append(output, typedDecl(v))
+	}
+	return output
+}
+
+func typedMaxThree(node *ASTNode) []*VarDecl {
+	if node == nil || node.Type != "MAXTHREE" || len(node.Children) > 3 {
+		panic("expected MAXTHREE with at most 3 variables")
+	}
+	output := make([]*VarDecl, 0)
+	for _, v := range node.Children {
+		output = append(output, typedDecl(v))
+	}
+	return output
+}
+
+func typedDecl(node *ASTNode) *VarDecl {
+	expect(node, "VAR", 0)
+	return &VarDecl{view{node}, node.Name}
+}
+
+func typedName(node *ASTNode) *Ident {
+	expect(node, "NAME", 0)
+	return &Ident{view{node}, node.Name}
+}
+
+func typedDefinition(def *ASTNode) ([]*VarDecl, []*VarDecl, []Stmt) {
+	param := def.Children[1]
+	expect(param, "PARAM", 1)
+	body := def.Children[2]
+	expect(body, "BODY", 2)
+	return typedMaxThree(param.Children[0]), typedMaxThree(body.Children[0]), typedAlgo(body.Children[1])
+}
+
+func typedAlgo(node *ASTNode) []Stmt {
+	if len(list(node, "ALGO")) == 0 {
+		panic(fmt.Sprintf("node %d: ALGO without instructions", node.ID))
+	}
+	output := make([]Stmt, 0)
+	for _, instr := range node.Children {
+		output = append(output, typedStmt(instr))
+	}
+	return output
+}
+
+func typedStmt(node *ASTNode) Stmt {
+	if node == nil || node.Type != "INSTR" {
+		panic("expected INSTR")
+	}
+	switch node.Name {
+	case "halt":
+		return &Halt{view{node}}
+	case "print":
+		expect(node, "INSTR", 1)
+		// A string is held in the name of a childless OUTPUT node, so it
+		// can read "atom" too.
+		output := node.Children[0]
+		if len(output.Children) == 1 {
+			expect(output, "OUTPUT", 1)
+			return &Print{view{node}, typedAtom(output.Children[0])}
+		}
+		return &Print{view{node}, &StringLit{view{output}, output.Name}}
+	case "call":
+		expect(node, "INSTR", 2)
+		return &CallStmt{view{node}, typedName(node.Children[0]), typedInput(node.Children[1])}
+	case "assign":
+		expect(node, "INSTR", 1)
+		assign := node.Children[0]
+		expectName(assign, "assignment", "call", "")
+		if assign.Name == "call" {
+			expect(assign, "ASSIGN", 3)
+			return &CallAssign{view{node}, typedRef(assign.Children[0]), typedName(assign.Children[1]), typedInput(assign.Children[2])}
+		}
+		expect(assign, "ASSIGN", 2)
+		return &Assign{view{node}, typedRef(assign.Children[0]), typedTerm(assign.Children[1])}
+	case "loop":
+		expect(node, "INSTR", 1)
+		loop := node.Children[0]
+		expect(loop, "LOOP", 2)
+		expectName(loop, "loop", "while", "do")
+		if loop.Name == "do" {
+			return &DoUntil{view{node}, typedAlgo(loop.Children[0]), typedTerm(loop.Children[1])}
+		}
+		return &While{view{node}, typedTerm(loop.Children[0]), typedAlgo(loop.Children[1])}
+	case "branch":
+		expect(node, "INSTR", 1)
+		branch := node.Children[0]
+		expectName(branch, "branch", "if", "ifelse")
+		if branch.Name == "ifelse" {
+			expect(branch, "BRANCH", 3)
+			return &If{view{node}, typedTerm(branch.Children[0]), typedAlgo(branch.Children[1]), typedAlgo(branch.Children[2])}
+		}
+		expect(branch, "BRANCH", 2)
+		return &If{view{node}, typedTerm(branch.Children[0]), typedAlgo(branch.Children[1]), nil}
+	default:
+		panic(fmt.Sprintf("node %d: unknown instruction %q", node.ID, node.Name))
+	}
+}
+
+func typedInput(node *ASTNode) []Atom {
+	if node == nil || node.Type != "INPUT" || len(node.Children) > 3 {
+		panic("expected INPUT with at most 3 arguments")
+	}
+	output := make([]Atom, 0)
+	for _, atom := range node.Children {
+		output = append(output, typedAtom(atom))
+	}
+	return output
+}
+
+func typedTerm(node *ASTNode) Expr {
+	if node == nil || node.Type != "TERM" {
+		panic("expected TERM")
+	}
+	switch node.Name {
+	case "atom":
+		expect(node, "TERM", 1)
+		return typedAtom(node.Children[0])
+	case "unop":
+		expect(node, "TERM", 2)
+		expect(node.Children[0], "UNOP", 0)
+		expectName(node.Children[0], "operator", "neg", "not")
+		return &UnaryExpr{view{node}, node.Children[0].Name, typedTerm(node.Children[1])}
+	case "binop":
+		expect(node, "TERM", 3)
+		expect(node.Children[1], "BINOP", 0)
+		expectName(node.Children[1], "operator", "eq", ">", "or", "and", "plus", "minus", "mult", "div")
+		return &BinaryExpr{view{node}, node.Children[1].Name, typedTerm(node.Children[0]), typedTerm(node.Children[2])}
+	default:
+		panic(fmt.Sprintf("node %d: unknown term %q", node.ID, node.Name))
+	}
+}
+
+func typedAtom(node *ASTNode) Atom {
+	if node == nil || node.Type != "ATOM" {
+		panic("expected ATOM")
+	}
+	if node.Name == "Var" {
+		expect(node, "ATOM", 1)
+		return typedRef(node.Children[0])
+	}
+	expect(node, "ATOM", 0)
+	return &NumberLit{view{node}, node.Name}
+}
+
+func typedRef(node *ASTNode) *VarRef {
+	expect(node, "VAR", 0)
+	return &VarRef{view{node}, node.Name}
+}
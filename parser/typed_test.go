@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	program, err := ParseProgram(`glob { g }
+proc {
+	p(a b) { local { t } t = (a plus b); print t }
+}
+func {
+	f(n) { local { } p(n 1); return n }
+}
+main {
+	var { x }
+	x = f(3);
+	while (x > 0) { x = (x minus 1) };
+	do { halt } until (not (x eq 0));
+	if (x > 1) { print "big" } else { print x }
+}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(program.Globals) != 1 || program.Globals[0].Name != "g" {
+		t.Errorf("got globals %v", program.Globals)
+	}
+	if len(program.Procs) != 1 || len(program.Funcs) != 1 {
+		t.Fatalf("got %d procedures and %d functions", len(program.Procs), len(program.Funcs))
+	}
+	proc := program.Procs[0]
+	if proc.Name.Name != "p" || len(proc.Params) != 2 || len(proc.Locals) != 1 || len(proc.Body) != 2 {
+		t.Errorf("unexpected procedure %+v", proc)
+	}
+	if sum, ok := proc.Body[0].(*Assign).Value.(*BinaryExpr); !ok || sum.Op != "plus" || sum.Left.(*VarRef).Name != "a" {
+		t.Errorf("unexpected assignment value %+v", proc.Body[0].(*Assign).Value)
+	}
+	fn := program.Funcs[0]
+	if call, ok := fn.Body[0].(*CallStmt); !ok || call.Name.Name != "p" || call.Args[1].(*NumberLit).Value != "1" {
+		t.Errorf("unexpected call %+v", fn.Body[0])
+	}
+	if fn.Return.(*VarRef).Name != "n" {
+		t.Errorf("unexpected return %+v", fn.Return)
+	}
+
+	body := program.Main.Body
+	if len(body) != 4 {
+		t.Fatalf("got %d main instructions", len(body))
+	}
+	if call, ok := body[0].(*CallAssign); !ok || call.Target.Name != "x" || call.Name.Name != "f" || len(call.Args) != 1 {
+		t.Errorf("unexpected call assignment %+v", body[0])
+	}
+	if loop, ok := body[1].(*While); !ok || loop.Cond.(*BinaryExpr).Op != ">" || len(loop.Body) != 1 {
+		t.Errorf("unexpected while loop %+v", body[1])
+	}
+	if loop, ok := body[2].(*DoUntil); !ok || loop.Cond.(*UnaryExpr).Op != "not" {
+		t.Errorf("unexpected do loop %+v", body[2])
+	} else if _, ok := loop.Body[0].(*Halt); !ok {
+		t.Errorf("unexpected do loop body %+v", loop.Body)
+	}
+	branch, ok := body[3].(*If)
+	if !ok || len(branch.Then) != 1 || len(branch.Else) != 1 {
+		t.Fatalf("unexpected branch %+v", body[3])
+	}
+	if value := branch.Then[0].(*Print).Value.(*StringLit).Value; value != "big" {
+		t.Errorf("got string %s", value)
+	}
+
+	instr := branch.Generic()
+	if instr.Type != "INSTR" || instr.Name != "branch" || instr.Span != branch.Span() {
+		t.Errorf("typed branch views %s: %s", instr.Type, instr.Name)
+	}
+}
+
+func TestTypedStrings(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"Testing a string named like an atom output", "atom"},
+		{"Testing a string named like a call", "call"},
+		{"Testing a string named like an instruction", "halt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := ParseProgram("glob { }\nproc { }\nfunc { }\nmain { var { } print \"" + tt.value + "\" }")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if value, ok := program.Main.Body[0].(*Print).Value.(*StringLit); !ok || value.Value != tt.value {
+				t.Errorf("got print value %+v", program.Main.Body[0].(*Print).Value)
+			}
+		})
+	}
+}
+
+func TestTypedMalformed(t *testing.T) {
+	root := NewNode("SPL_PROG", "", NewNode("VARIABLES", ""))
+	if _, err := ValidateTyped(root); err == nil || !strings.Contains(err.Error(), "expected SPL_PROG with 4 children") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestTypedAgreesWithParser(t *testing.T) {
+	program, err := ParseProgram(`glob { g }
+proc {
+	p(a) { local { } if (a > 0) { g = (neg a) } else { print "none" } }
+}
+func {
+	f(n) { local { m } m = n; do { m = (m minus 1) } until (m eq 0); return 3 }
+}
+main { var { x } x = f(g); p(x) }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	adapted := Typed(program.Generic())
+	if got, want := FormatProgram(adapted, nil), FormatProgram(program, nil); got != want {
+		t.Errorf("the adapted tree formats as\n%s\nthe parsed one as\n%s", got, want)
+	}
+	for i, stmt := range program.Main.Body {
+		if adapted.Main.Body[i].Generic() != stmt.Generic() {
+			t.Errorf("instruction %d views a different node", i)
+		}
+	}
+	if adapted.Funcs[0].Return.Generic() != program.Funcs[0].Return.Generic() {
+		t.Errorf("the return views a different node")
+	}
+}
@@ -0,0 +1,143 @@
+package parser
+
+import "fmt"
+
+// Walk traverses a tree depth-first. pre is called on a node before its
+// children and post after them; either may be nil. When pre returns false
+// the children are skipped, but post is still called.
+func Walk(node *ASTNode, pre func(*ASTNode) bool, post func(*ASTNode)) {
+	if node == nil {
+		return
+	}
+	if pre == nil || pre(node) {
+		for _, child := range node.Children {
+			Walk(child, pre, post)
+		}
+	}
+	if post != nil {
+		post(node)
+	}
+}
+
+// Inspect calls f on every node of a tree in depth-first order, skipping
+// the children of nodes for which f returns false.
+func Inspect(node *ASTNode, f func(*ASTNode) bool) {
+	Walk(node, f, nil)
+}
+
+// Visitor has a method per node type. Each is called before the node's
+// children and returns whether to visit them; Leave is called on every node
+// after its children. Embed BaseVisitor to override only some of them.
+type Visitor interface {
+	VisitProgram(node *ASTNode) bool
+	VisitVariables(node *ASTNode) bool
+	VisitVar(node *ASTNode) bool
+	VisitName(node *ASTNode) bool
+	VisitProcDefs(node *ASTNode) bool
+	VisitProcDef(node *ASTNode) bool
+	VisitFuncDefs(node *ASTNode) bool
+	VisitFuncDef(node *ASTNode) bool
+	VisitBody(node *ASTNode) bool
+	VisitParam(node *ASTNode) bool
+	VisitMaxThree(node *ASTNode) bool
+	VisitMain(node *ASTNode) bool
+	VisitAtom(node *ASTNode) bool
+	VisitAlgo(node *ASTNode) bool
+	VisitInstr(node *ASTNode) bool
+	VisitAssign(node *ASTNode) bool
+	VisitLoop(node *ASTNode) bool
+	VisitBranch(node *ASTNode) bool
+	VisitOutput(node *ASTNode) bool
+	VisitInput(node *ASTNode) bool
+	VisitTerm(node *ASTNode) bool
+	VisitUnOp(node *ASTNode) bool
+	VisitBinOp(node *ASTNode) bool
+	Leave(node *ASTNode)
+}
+
+// BaseVisitor visits every node and does nothing.
+type BaseVisitor struct{}
+
+func (BaseVisitor) VisitProgram(*ASTNode) bool   { return true }
+func (BaseVisitor) VisitVariables(*ASTNode) bool { return true }
+func (BaseVisitor) VisitVar(*ASTNode) bool       { return true }
+func (BaseVisitor) VisitName(*ASTNode) bool      { return true }
+func (BaseVisitor) VisitProcDefs(*ASTNode) bool  { return true }
+func (BaseVisitor) VisitProcDef(*ASTNode) bool   { return true }
+func (BaseVisitor) VisitFuncDefs(*ASTNode) bool  { return true }
+func (BaseVisitor) VisitFuncDef(*ASTNode) bool   { return true }
+func (BaseVisitor) VisitBody(*ASTNode) bool      { return true }
+func (BaseVisitor) VisitParam(*ASTNode) bool     { return true }
+func (BaseVisitor) VisitMaxThree(*ASTNode) bool  { return true }
+func (BaseVisitor) VisitMain(*ASTNode) bool      { return true }
+func (BaseVisitor) VisitAtom(*ASTNode) bool      { return true }
+func (BaseVisitor) VisitAlgo(*ASTNode) bool      { return true }
+func (BaseVisitor) VisitInstr(*ASTNode) bool     { return true }
+func (BaseVisitor) VisitAssign(*ASTNode) bool    { return true }
+func (BaseVisitor) VisitLoop(*ASTNode) bool      { return true }
+func (BaseVisitor) VisitBranch(*ASTNode) bool    { return true }
+func (BaseVisitor) VisitOutput(*ASTNode) bool    { return true }
+func (BaseVisitor) VisitInput(*ASTNode) bool     { return true }
+func (BaseVisitor) VisitTerm(*ASTNode) bool      { return true }
+func (BaseVisitor) VisitUnOp(*ASTNode) bool      { return true }
+func (BaseVisitor) VisitBinOp(*ASTNode) bool     { return true }
+func (BaseVisitor) Leave(*ASTNode)               {}
+
+// Accept walks a tree with a visitor, panicking on a node type it has no
+// method for.
+func Accept(node *ASTNode, v Visitor) {
+	Walk(node, func(node *ASTNode) bool { return dispatch(node, v) }, v.Leave)
+}
+
+func dispatch(node *ASTNode, v Visitor) bool {
+	switch node.Type {
+	case "SPL_PROG":
+		return v.VisitProgram(node)
+	case "VARIABLES":
+		return v.VisitVariables(node)
+	case "VAR":
+		return v.VisitVar(node)
+	case "NAME":
+		return v.VisitName(node)
+	case "PROCDEFS":
+		return v.VisitProcDefs(node)
+	case "PDEF":
+		return v.VisitProcDef(node)
+	case "FUNCDEFS":
+		return v.VisitFuncDefs(node)
+	case "FDEF":
+		return v.VisitFuncDef(node)
+	case "BODY":
+		return v.VisitBody(node)
+	case "PARAM":
+		return v.VisitParam(node)
+	case "MAXTHREE":
+		return v.VisitMaxThree(node)
+	case "MAINPROG":
+		return v.VisitMain(node)
+	case "ATOM":
+		return v.VisitAtom(node)
+	case "ALGO":
+		return v.VisitAlgo(node)
+	case "INSTR":
+		return v.VisitInstr(node)
+	case "ASSIGN":
+		return v.VisitAssign(node)
+	case "LOOP":
+		return v.VisitLoop(node)
+	case "BRANCH":
+		return v.VisitBranch(node)
+	case "OUTPUT":
+		return v.VisitOutput(node)
+	case "INPUT":
+		return v.VisitInput(node)
+	case "TERM":
+		return v.VisitTerm(node)
+	case "UNOP":
+		return v.VisitUnOp(node)
+	case "BINOP":
+		return v.VisitBinOp(node)
+	default:
+		panic(fmt.Sprintf("unvisited-node-type: %s", node.Type))
+	}
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	root := NewNode("ALGO", "",
+		NewNode("INSTR", "halt"),
+		NewNode("INSTR", "print", NewNode("OUTPUT", "hi")))
+
+	var pre, post []string
+	Walk(root, func(node *ASTNode) bool {
+		pre = append(pre, node.Type+":"+node.Name)
+		return node.Name != "print"
+	}, func(node *ASTNode) {
+		post = append(post, node.Type+":"+node.Name)
+	})
+
+	if expected := []string{"ALGO:", "INSTR:halt", "INSTR:print"}; !reflect.DeepEqual(pre, expected) {
+		t.Errorf("got pre-order %v, expected %v", pre, expected)
+	}
+	if expected := []string{"INSTR:halt", "INSTR:print", "ALGO:"}; !reflect.DeepEqual(post, expected) {
+		t.Errorf("got post-order %v, expected %v", post, expected)
+	}
+}
+
+// instrCounter overrides only the node types it cares about.
+type instrCounter struct {
+	BaseVisitor
+	instrs int
+	depth  int
+	max    int
+}
+
+func (c *instrCounter) VisitInstr(*ASTNode) bool {
+	c.instrs++
+	return true
+}
+
+func (c *instrCounter) VisitAlgo(*ASTNode) bool {
+	c.depth++
+	c.max = max(c.max, c.depth)
+	return true
+}
+
+func (c *instrCounter) VisitTerm(*ASTNode) bool {
+	return false
+}
+
+func (c *instrCounter) Leave(node *ASTNode) {
+	if node.Type == "ALGO" {
+		c.depth--
+	}
+}
+
+func TestAccept(t *testing.T) {
+	root, err := Validate(`glob { x }
+proc { }
+func { }
+main {
+	var { }
+	x = 1;
+	while ((x > 0) and (x > 1)) { x = (x minus 1); print x };
+	halt
+}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	counter := &instrCounter{}
+	Accept(root, counter)
+	if counter.instrs != 5 || counter.max != 2 || counter.depth != 0 {
+		t.Errorf("counted %d instructions with %d nested lists, ending at depth %d", counter.instrs, counter.max, counter.depth)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected a panic for an unknown node type")
+		}
+	}()
+	Accept(NewNode("ALGO", "", NewNode("GOTO", "")), BaseVisitor{})
+}
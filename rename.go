@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"SPL-compiler/analyser"
+	"SPL-compiler/parser"
+)
+
+// rename implements `spl rename [-w | -o file] file.spl line:col newname`,
+// which renames the variable, procedure or function at the position and
+// writes the program to stdout, the -o file or, with -w, back to its file.
+func rename(args []string) {
+	flags := flag.NewFlagSet("rename", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result back to the file")
+	output := flags.String("o", "", "output file, stdout when empty")
+	flags.Parse(args)
+
+	if flags.NArg() != 3 || (*write && *output != "") {
+		fmt.Fprintln(os.Stderr, "usage: spl rename [-w | -o file] file.spl line:col newname")
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+	filename := flags.Arg(0)
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+	offset, err := sourceOffset(string(content), flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Position error:", err)
+		os.Exit(2)
+	}
+
+	analysis := analyser.Analyse(string(content))
+	var node *parser.ASTNode
+	if analysis.Root != nil {
+		node = parser.NodeAt(analysis.Root, offset)
+	}
+	code, _, err := analysis.Rename(node, flags.Arg(2))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:%s: Rename error: %v\n", filename, flags.Arg(1), err)
+		os.Exit(1)
+	}
+
+	switch {
+	case *write:
+		writeToFile(filename, code)
+	case *output != "":
+		writeToFile(*output, code)
+	default:
+		fmt.Print(code)
+	}
+}
+
+// sourceOffset converts a 1-based line:col position into a byte offset.
+func sourceOffset(source, position string) (int, error) {
+	lineText, columnText, ok := strings.Cut(position, ":")
+	line, lineErr := strconv.Atoi(lineText)
+	column, columnErr := strconv.Atoi(columnText)
+	if !ok || lineErr != nil || columnErr != nil || line < 1 || column < 1 {
+		return 0, fmt.Errorf("expected line:col, got %q", position)
+	}
+	lines := strings.SplitAfter(source, "\n")
+	if line > len(lines) || column > len(strings.TrimSuffix(lines[line-1], "\n"))+1 {
+		return 0, fmt.Errorf("%s is outside the file", position)
+	}
+	offset := 0
+	for _, text := range lines[:line-1] {
+		offset += len(text)
+	}
+	return offset + column - 1, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"SPL-compiler/analyser"
+)
+
+// renumber implements `spl renumber [flags] file.bas`, which renumbers an
+// existing BASIC program and writes it to stdout or the -o file.
+func renumber(args []string) {
+	flags := flag.NewFlagSet("renumber", flag.ExitOnError)
+	start := flags.Int("start", analyser.DefaultLineNumbering.Start, "first line number")
+	increment := flags.Int("increment", analyser.DefaultLineNumbering.Increment, "step between line numbers")
+	limit := flags.Int("max", 0, "highest line number allowed, 0 for no limit")
+	align := flags.Bool("align", false, "pad line numbers to a common width")
+	output := flags.String("o", "", "output file, stdout when empty")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Println("usage: spl renumber [flags] file.bas")
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+	numbering := analyser.LineNumbering{Start: *start, Increment: *increment, Max: *limit}
+	lines, err := analyser.ValidateRenumberBasic(strings.Split(string(content), "\n"), numbering, *align)
+	if err != nil {
+		fmt.Println("Renumbering error:", err)
+		os.Exit(1)
+	}
+
+	code := strings.Join(lines, "\n") + "\n"
+	if *output == "" {
+		fmt.Print(code)
+	} else {
+		writeToFile(*output, code)
+	}
+}
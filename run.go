@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"SPL-compiler/bytecode"
+)
+
+// run implements `spl run [flags] file.splc`, which executes a program
+// compiled by the bytecode backend, or with -d lists its instructions.
+func run(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	maxSteps := flags.Int64("max-steps", 0, "stop after this many instructions, 0 for no limit")
+	disassemble := flags.Bool("d", false, "print the instructions instead of running them")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: spl run [flags] file.splc")
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+	program, err := bytecode.Decode(content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: Bytecode error: %v\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+	if *disassemble {
+		fmt.Print(program.Disassemble())
+		return
+	}
+
+	vm := &bytecode.VM{Stdout: os.Stdout, MaxSteps: *maxSteps}
+	if err := vm.Run(program); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: Runtime error: %v\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,63 @@
+// Package wasmrun executes modules produced by the WebAssembly backend with
+// the pure-Go wazero runtime, so they can be run without a browser.
+package wasmrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Run instantiates a binary module, provides the "spl" host imports and calls
+// the exported main function. Output of print is written to stdout.
+func Run(ctx context.Context, wasm []byte, stdout io.Writer) error {
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	_, err := r.NewHostModuleBuilder("spl").
+		NewFunctionBuilder().
+		WithFunc(func(v int64) {
+			fmt.Fprintln(stdout, v)
+		}).
+		Export("print_int").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, ptr, length uint32) {
+			text, ok := m.Memory().Read(ptr, length)
+			if !ok {
+				panic(fmt.Sprintf("print_str out of bounds: %d+%d", ptr, length))
+			}
+			fmt.Fprintln(stdout, string(text))
+		}).
+		Export("print_str").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module) {
+			_ = m.CloseWithExitCode(ctx, 0)
+			panic(sys.NewExitError(0))
+		}).
+		Export("halt").
+		Instantiate(ctx)
+	if err != nil {
+		return err
+	}
+
+	mod, err := r.Instantiate(ctx, wasm)
+	if err != nil {
+		return err
+	}
+	main := mod.ExportedFunction("main")
+	if main == nil {
+		return errors.New("module does not export main")
+	}
+
+	_, err = main.Call(ctx)
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+		return nil
+	}
+	return err
+}